@@ -12,8 +12,37 @@ type Config struct {
 	Dispatcher  DispatcherConfig
 	WeChat      WeChatConfig
 	DingTalk    DingTalkConfig
+	Feishu      FeishuConfig
+	Slack       SlackConfig
+	Telegram    TelegramConfig
+	Discord     DiscordConfig
+	Email       EmailConfig
 	Log         logger.LogConfig
 	HealthCheck HealthCheckConfig
+	Channels    []ChannelConfig
+	HTTP        HTTPConfig // 各发送器 HTTP 客户端的默认配置
+}
+
+// HTTPConfig 发送器的 HTTP 客户端配置
+type HTTPConfig struct {
+	BaseURL string        `mapstructure:"base_url"` // 接口地址，为空时使用平台官方地址，不从全局配置继承
+	Timeout time.Duration `mapstructure:"timeout"`  // 请求超时时间，默认 10s
+	Proxy   string        `mapstructure:"proxy"`    // HTTP(S) 代理地址，为空时使用 HTTPS_PROXY 等环境变量
+	CAFile  string        `mapstructure:"ca_file"`  // 额外信任的 CA 证书文件（PEM 格式）
+}
+
+// withDefaults 未配置的字段使用全局默认值
+func (c HTTPConfig) withDefaults(defaults HTTPConfig) HTTPConfig {
+	if c.Timeout == 0 {
+		c.Timeout = defaults.Timeout
+	}
+	if c.Proxy == "" {
+		c.Proxy = defaults.Proxy
+	}
+	if c.CAFile == "" {
+		c.CAFile = defaults.CAFile
+	}
+	return c
 }
 
 type ServerConfig struct {
@@ -22,35 +51,139 @@ type ServerConfig struct {
 	ReadTimeout  time.Duration `mapstructure:"read_timeout"`
 	WriteTimeout time.Duration `mapstructure:"write_timeout"`
 	Token        string        `mapstructure:"token"`
+	RetryAfter   time.Duration `mapstructure:"retry_after"` // 队列已满时建议客户端的重试间隔
+
+	ShutdownTimeout time.Duration `mapstructure:"shutdown_timeout"` // 关闭时等待处理中请求完成的最长时间
 }
 
 type DispatcherConfig struct {
-	BufferSize     int `mapstructure:"buffer_size"`
-	WorkerPoolSize int `mapstructure:"worker_pool_size"`
+	BufferSize        int                      `mapstructure:"buffer_size"`
+	WorkerPoolSize    int                      `mapstructure:"worker_pool_size"`
+	Overflow          string                   `mapstructure:"overflow"`           // 队列已满时的处理方式：reject（立即拒绝）, block（阻塞等待）
+	EnqueueTimeout    time.Duration            `mapstructure:"enqueue_timeout"`    // block 模式下的最长等待时间
+	DrainTimeout      time.Duration            `mapstructure:"drain_timeout"`      // 停止时发送剩余消息的最长时间，超时后未发送的消息保留在持久化队列中
+	QueueDir          string                   `mapstructure:"queue_dir"`          // 持久化队列目录，为空时仅使用内存
+	IdempotencyWindow time.Duration            `mapstructure:"idempotency_window"` // 幂等键的去重窗口
+	StatusRetention   time.Duration            `mapstructure:"status_retention"`   // 已完成消息的状态保留时间
+	DefaultTTL        map[string]time.Duration `mapstructure:"default_ttl"`        // 各平台消息的默认有效期，键为平台名
+	Retry             RetryConfig              `mapstructure:"retry"`
+	Priority          PriorityConfig           `mapstructure:"priority"`
+}
+
+// PriorityConfig 优先级通道配置
+type PriorityConfig struct {
+	Buffers         map[string]int `mapstructure:"buffers"`          // 各优先级（high、normal、low）的缓冲区大小，未配置时使用 buffer_size
+	StarvationLimit int            `mapstructure:"starvation_limit"` // 单个低优先级通道被连续跳过的次数上限，达到后强制处理该通道的一条消息
+}
+
+// RetryConfig 发送失败后的重试策略，采用带抖动的指数退避
+type RetryConfig struct {
+	MaxAttempts    int           `mapstructure:"max_attempts"`    // 最大尝试次数（含首次发送）
+	InitialBackoff time.Duration `mapstructure:"initial_backoff"` // 首次重试等待时间
+	MaxBackoff     time.Duration `mapstructure:"max_backoff"`     // 单次等待时间上限
+	Multiplier     float64       `mapstructure:"multiplier"`      // 退避倍数
+	Jitter         float64       `mapstructure:"jitter"`          // 抖动比例，取值 0~1
+	MaxElapsed     time.Duration `mapstructure:"max_elapsed"`     // 自入队（定时消息自计划发送时间）起的最长重试时间，0 表示不限制
+}
+
+// ChannelConfig 命名通道，同一平台可以配置多个机器人或应用，各自使用独立的凭证
+type ChannelConfig struct {
+	Name     string         `mapstructure:"name"`     // 通道名，请求中通过 channel 字段指定
+	Platform string         `mapstructure:"platform"` // 所属平台：dingtalk, wechat, feishu, slack, telegram, discord, email
+	DingTalk DingTalkConfig `mapstructure:"dingtalk"`
+	Feishu   FeishuConfig   `mapstructure:"feishu"`
+	Slack    SlackConfig    `mapstructure:"slack"`
+	Telegram TelegramConfig `mapstructure:"telegram"`
+	Discord  DiscordConfig  `mapstructure:"discord"`
+	Email    EmailConfig    `mapstructure:"email"`
+	WeChat   WeChatConfig   `mapstructure:"wechat"`
 }
 
 type WeChatConfig struct {
 	SenderType string         `mapstructure:"sender_type"`
 	WeCom      WeComConfig    `mapstructure:"wecom"`
 	WxPusher   WxPusherConfig `mapstructure:"wxpusher"`
+	WeComBot   WeComBotConfig `mapstructure:"wecom_bot"`
 }
 
 type WeComConfig struct {
-	CorpID    string `mapstructure:"corp_id"`
-	AgentID   string `mapstructure:"agent_id"`
-	AppSecret string `mapstructure:"app_secret"`
+	CorpID    string     `mapstructure:"corp_id"`
+	AgentID   string     `mapstructure:"agent_id"`
+	AppSecret string     `mapstructure:"app_secret"`
+	HTTP      HTTPConfig `mapstructure:"http"`
+	Oversize  string     `mapstructure:"oversize"` // 超长正文的处理方式：split, truncate
+}
+
+// WeComBotConfig 企业微信群机器人配置，只需要 webhook 地址中的 key
+type WeComBotConfig struct {
+	Key      string     `mapstructure:"key"`
+	HTTP     HTTPConfig `mapstructure:"http"`
+	Oversize string     `mapstructure:"oversize"` // 超长正文的处理方式：split, truncate
 }
 
 type WxPusherConfig struct {
-	AppToken string  `mapstructure:"app_token"`
-	TopicIDs []int64 `mapstructure:"topic_ids"`
-	QPS      int     `mapstructure:"qps"`
-	ApiUrl   string  `mapstructure:"api_url"`
+	AppToken string     `mapstructure:"app_token"`
+	TopicIDs []int64    `mapstructure:"topic_ids"`
+	QPS      int        `mapstructure:"qps"`
+	ApiUrl   string     `mapstructure:"api_url"` // 完整的发送接口地址，优先于 http.base_url
+	HTTP     HTTPConfig `mapstructure:"http"`
+	Oversize string     `mapstructure:"oversize"` // 超长正文的处理方式：split, truncate
 }
 
 type DingTalkConfig struct {
-	AccessToken string `mapstructure:"access_token"`
-	Secret      string `mapstructure:"secret"`
+	AccessToken string     `mapstructure:"access_token"`
+	Secret      string     `mapstructure:"secret"`
+	HTTP        HTTPConfig `mapstructure:"http"`
+	Oversize    string     `mapstructure:"oversize"` // 正文超过平台长度限制时的处理方式：split（拆分，默认）, truncate（截断）
+}
+
+// FeishuConfig 飞书自定义机器人配置
+type FeishuConfig struct {
+	Token    string     `mapstructure:"token"`  // webhook 地址 /open-apis/bot/v2/hook/ 之后的部分
+	Secret   string     `mapstructure:"secret"` // 签名校验密钥，未开启签名校验时留空
+	HTTP     HTTPConfig `mapstructure:"http"`
+	Oversize string     `mapstructure:"oversize"` // 超长正文的处理方式：split, truncate
+}
+
+// SlackConfig Slack 配置，配置了 bot_token 时通过 chat.postMessage 发送，否则使用 incoming webhook
+type SlackConfig struct {
+	WebhookURL string     `mapstructure:"webhook_url"` // incoming webhook 地址
+	BotToken   string     `mapstructure:"bot_token"`   // 机器人 token（xoxb-）
+	Channel    string     `mapstructure:"channel"`     // 默认频道，请求可通过 extra.channel 覆盖，仅 bot_token 方式有效
+	HTTP       HTTPConfig `mapstructure:"http"`
+	Oversize   string     `mapstructure:"oversize"` // 超长正文的处理方式：split, truncate
+}
+
+// TelegramConfig Telegram 机器人配置
+type TelegramConfig struct {
+	BotToken  string     `mapstructure:"bot_token"`
+	ChatIDs   []string   `mapstructure:"chat_ids"`   // 默认接收的 chat_id，请求可通过 extra.chat_id 覆盖
+	ParseMode string     `mapstructure:"parse_mode"` // 纯文本正文的渲染方式：HTML（默认）, MarkdownV2
+	HTTP      HTTPConfig `mapstructure:"http"`
+	Oversize  string     `mapstructure:"oversize"` // 超长正文的处理方式：split, truncate
+}
+
+// DiscordConfig Discord 频道 webhook 配置
+type DiscordConfig struct {
+	WebhookURL string     `mapstructure:"webhook_url"`
+	Username   string     `mapstructure:"username"`   // 覆盖 webhook 默认的显示名称
+	AvatarURL  string     `mapstructure:"avatar_url"` // 覆盖 webhook 默认的头像
+	HTTP       HTTPConfig `mapstructure:"http"`
+	Oversize   string     `mapstructure:"oversize"` // 超长正文的处理方式：split, truncate
+}
+
+// EmailConfig SMTP 邮件配置
+type EmailConfig struct {
+	Host     string        `mapstructure:"host"`
+	Port     int           `mapstructure:"port"`     // 默认按加密方式取 25、587 或 465
+	Security string        `mapstructure:"security"` // 加密方式：none, starttls（默认）, tls（465 端口默认）
+	Username string        `mapstructure:"username"` // 为空时不认证
+	Password string        `mapstructure:"password"`
+	From     string        `mapstructure:"from"`    // 发件人，为空时使用 username
+	To       []string      `mapstructure:"to"`      // 默认收件人，请求可通过 extra.to/cc/bcc 覆盖
+	Timeout  time.Duration `mapstructure:"timeout"` // SMTP 会话超时时间，默认 30s
+	CAFile   string        `mapstructure:"ca_file"` // 额外信任的 CA 证书文件（PEM 格式）
+	HTTP     HTTPConfig    `mapstructure:"http"`    // 下载 media_url 附件使用的 HTTP 配置
 }
 
 type HealthCheckConfig struct {
@@ -78,5 +211,33 @@ func Load() (*Config, error) {
 		return nil, err
 	}
 
+	config.applyHTTPDefaults()
+
 	return &config, nil
 }
+
+// applyHTTPDefaults 将全局 HTTP 配置应用到各发送器和命名通道
+func (c *Config) applyHTTPDefaults() {
+	c.DingTalk.HTTP = c.DingTalk.HTTP.withDefaults(c.HTTP)
+	c.Feishu.HTTP = c.Feishu.HTTP.withDefaults(c.HTTP)
+	c.Slack.HTTP = c.Slack.HTTP.withDefaults(c.HTTP)
+	c.Telegram.HTTP = c.Telegram.HTTP.withDefaults(c.HTTP)
+	c.Discord.HTTP = c.Discord.HTTP.withDefaults(c.HTTP)
+	c.Email.HTTP = c.Email.HTTP.withDefaults(c.HTTP)
+	c.WeChat.applyHTTPDefaults(c.HTTP)
+	for i := range c.Channels {
+		c.Channels[i].DingTalk.HTTP = c.Channels[i].DingTalk.HTTP.withDefaults(c.HTTP)
+		c.Channels[i].Feishu.HTTP = c.Channels[i].Feishu.HTTP.withDefaults(c.HTTP)
+		c.Channels[i].Slack.HTTP = c.Channels[i].Slack.HTTP.withDefaults(c.HTTP)
+		c.Channels[i].Telegram.HTTP = c.Channels[i].Telegram.HTTP.withDefaults(c.HTTP)
+		c.Channels[i].Discord.HTTP = c.Channels[i].Discord.HTTP.withDefaults(c.HTTP)
+		c.Channels[i].Email.HTTP = c.Channels[i].Email.HTTP.withDefaults(c.HTTP)
+		c.Channels[i].WeChat.applyHTTPDefaults(c.HTTP)
+	}
+}
+
+func (c *WeChatConfig) applyHTTPDefaults(defaults HTTPConfig) {
+	c.WeCom.HTTP = c.WeCom.HTTP.withDefaults(defaults)
+	c.WeComBot.HTTP = c.WeComBot.HTTP.withDefaults(defaults)
+	c.WxPusher.HTTP = c.WxPusher.HTTP.withDefaults(defaults)
+}
@@ -18,6 +18,7 @@ type HealthChecker struct {
 	sender *sender.Manager
 	config config.HealthCheckConfig
 	stop   chan struct{}
+	done   chan struct{}
 }
 
 func NewHealthChecker(sender *sender.Manager, config config.HealthCheckConfig) *HealthChecker {
@@ -25,22 +26,28 @@ func NewHealthChecker(sender *sender.Manager, config config.HealthCheckConfig) *
 		sender: sender,
 		config: config,
 		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
 	}
 }
 
 func (h *HealthChecker) Start() {
 	if !h.config.Enabled {
 		logger.Info("Health check is disabled")
+		close(h.done)
 		return
 	}
 	go h.run()
 }
 
+// Stop 停止健康检查，并等待正在进行的检查结束
 func (h *HealthChecker) Stop() {
 	close(h.stop)
+	<-h.done
 }
 
 func (h *HealthChecker) run() {
+	defer close(h.done)
+
 	// 解析配置的检查时间
 	checkTime := strings.Split(h.config.CheckTime, ":")
 	if len(checkTime) != 2 {
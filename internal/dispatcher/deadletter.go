@@ -0,0 +1,95 @@
+package dispatcher
+
+import (
+	"fmt"
+	"time"
+
+	"notify/internal/queue"
+	"notify/pkg/logger"
+
+	"go.uber.org/zap"
+)
+
+// DeadLetters 返回所有死信消息
+func (d *Dispatcher) DeadLetters() []*queue.Entry {
+	return d.deadLetters.List()
+}
+
+// DeadLetter 返回指定的死信消息
+func (d *Dispatcher) DeadLetter(id string) (*queue.Entry, bool) {
+	return d.deadLetters.Get(id)
+}
+
+// ReplayDeadLetter 将死信消息重新放回待发送队列，尝试次数从零开始计算
+func (d *Dispatcher) ReplayDeadLetter(id string) error {
+	entry, ok := d.deadLetters.Get(id)
+	if !ok {
+		return ErrNotFound
+	}
+	return d.replayDeadLetter(entry)
+}
+
+// ReplayDeadLetters 重新发送所有死信消息，返回成功放回队列的数量
+func (d *Dispatcher) ReplayDeadLetters() (int, error) {
+	count := 0
+	for _, entry := range d.deadLetters.List() {
+		if err := d.replayDeadLetter(entry); err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, nil
+}
+
+// PurgeDeadLetter 删除指定的死信消息
+func (d *Dispatcher) PurgeDeadLetter(id string) error {
+	if _, ok := d.deadLetters.Get(id); !ok {
+		return ErrNotFound
+	}
+	if err := d.deadLetters.Delete(id); err != nil {
+		return fmt.Errorf("delete dead letter failed: %w", err)
+	}
+	return nil
+}
+
+// PurgeDeadLetters 删除所有死信消息，返回删除的数量
+func (d *Dispatcher) PurgeDeadLetters() (int, error) {
+	count := 0
+	for _, entry := range d.deadLetters.List() {
+		if err := d.deadLetters.Delete(entry.ID); err != nil {
+			return count, fmt.Errorf("delete dead letter failed: %w", err)
+		}
+		count++
+	}
+	return count, nil
+}
+
+func (d *Dispatcher) replayDeadLetter(entry *queue.Entry) error {
+	entry.EnqueuedAt = time.Now()
+	entry.Attempts = nil
+
+	// 人工重发时不再受原有效期限制，复制消息避免修改存储中的记录
+	if entry.Message.ExpiresAt != nil {
+		msg := *entry.Message
+		msg.ExpiresAt = nil
+		entry.Message = &msg
+	}
+
+	// 先写入待发送队列再删除死信，中途崩溃最多导致重复而不会丢失
+	if err := d.store.Put(entry); err != nil {
+		return fmt.Errorf("persist message failed: %w", err)
+	}
+	if err := d.deadLetters.Delete(entry.ID); err != nil {
+		return fmt.Errorf("delete dead letter failed: %w", err)
+	}
+
+	d.status.update(entry, StateQueued)
+
+	// 通过延迟队列投递，工作通道已满时不会阻塞调用方
+	d.delayed.push(entry, entry.EnqueuedAt)
+
+	logger.Info("Dead letter replayed",
+		zap.String("id", entry.ID),
+		zap.String("platform", string(entry.Message.Platform)))
+	return nil
+}
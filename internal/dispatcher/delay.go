@@ -0,0 +1,118 @@
+package dispatcher
+
+import (
+	"container/heap"
+	"sync"
+	"time"
+
+	"notify/internal/queue"
+)
+
+// delayItem 等待到期的消息
+type delayItem struct {
+	entry *queue.Entry
+	at    time.Time
+}
+
+type delayHeap []*delayItem
+
+func (h delayHeap) Len() int           { return len(h) }
+func (h delayHeap) Less(i, j int) bool { return h[i].at.Before(h[j].at) }
+func (h delayHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *delayHeap) Push(x any)        { *h = append(*h, x.(*delayItem)) }
+func (h *delayHeap) Pop() any {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	old[n-1] = nil
+	*h = old[:n-1]
+	return item
+}
+
+// delayQueue 按到期时间排序的延迟队列，用于退避重试和定时发送
+type delayQueue struct {
+	mu    sync.Mutex
+	items delayHeap
+	wake  chan struct{}
+}
+
+func newDelayQueue() *delayQueue {
+	return &delayQueue{
+		wake: make(chan struct{}, 1),
+	}
+}
+
+// push 添加一条在 at 时刻到期的消息
+func (q *delayQueue) push(entry *queue.Entry, at time.Time) {
+	q.mu.Lock()
+	heap.Push(&q.items, &delayItem{entry: entry, at: at})
+	q.mu.Unlock()
+
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// remove 移除尚未到期的消息，消息已被取出时返回 false
+func (q *delayQueue) remove(id string) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for i, item := range q.items {
+		if item.entry.ID == id {
+			heap.Remove(&q.items, i)
+			return true
+		}
+	}
+	return false
+}
+
+// popDue 取出一条已到期的消息，没有时返回距离最近到期的等待时间，队列为空时等待时间为 -1
+func (q *delayQueue) popDue(now time.Time) (*queue.Entry, time.Duration) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if len(q.items) == 0 {
+		return nil, -1
+	}
+	if wait := q.items[0].at.Sub(now); wait > 0 {
+		return nil, wait
+	}
+	return heap.Pop(&q.items).(*delayItem).entry, 0
+}
+
+// run 持续将到期的消息交给 deliver，deliver 返回 false 或 stop 关闭时退出
+func (q *delayQueue) run(stop <-chan struct{}, deliver func(*queue.Entry) bool) {
+	for {
+		entry, wait := q.popDue(time.Now())
+		if entry != nil {
+			if !deliver(entry) {
+				return
+			}
+			continue
+		}
+
+		var timer *time.Timer
+		var timerC <-chan time.Time
+		if wait >= 0 {
+			timer = time.NewTimer(wait)
+			timerC = timer.C
+		}
+
+		select {
+		case <-stop:
+		case <-q.wake:
+		case <-timerC:
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+
+		select {
+		case <-stop:
+			return
+		default:
+		}
+	}
+}
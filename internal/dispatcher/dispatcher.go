@@ -2,81 +2,533 @@ package dispatcher
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"sync"
+	"sync/atomic"
+	"time"
 
+	"notify/internal/config"
 	"notify/internal/parser"
+	"notify/internal/queue"
 	"notify/internal/sender"
 	"notify/pkg/logger"
 
 	"go.uber.org/zap"
 )
 
+var (
+	// ErrNotFound 消息不存在
+	ErrNotFound = errors.New("message not found")
+	// ErrQueueFull 消息队列已满，调用方应稍后重试
+	ErrQueueFull = errors.New("message queue is full")
+	// ErrStopped 分发器已停止，不再接收新消息
+	ErrStopped = errors.New("dispatcher is stopped")
+)
+
+// dueRetryInterval 到期消息所在优先级的通道已满时，再次尝试投递的间隔
+const dueRetryInterval = 100 * time.Millisecond
+
+// 队列已满时的处理方式
+const (
+	OverflowReject = "reject" // 立即拒绝
+	OverflowBlock  = "block"  // 阻塞等待，超时后拒绝
+)
+
 type Dispatcher struct {
-	msgChan chan *parser.Message
-	sender  *sender.Manager
-	workers int
-	wg      sync.WaitGroup
+	lanes       []*lane // 按优先级从高到低排列
+	sender      *sender.Manager
+	store       queue.Store
+	deadLetters queue.Store
+	history     queue.Store    // 已完成消息的最终状态和幂等键，重启后仍可查询
+	pending     []*queue.Entry // 创建时持久化队列中遗留的消息，启动后回放
+	status      *statusTracker
+	idempotency *idempotencyCache
+	retry       retryPolicy
+	defaultTTL  map[string]time.Duration
+	delayed     *delayQueue
+	overflow    string
+	timeout     time.Duration // block 模式下的入队等待时间
+	starvation  int           // 单个低优先级通道被连续跳过的次数上限
+	drain       time.Duration // 停止时排空队列的最长时间
+	workers     int
+	wg          sync.WaitGroup
+	bg          sync.WaitGroup // 回放、延迟重试等后台协程
+	quit        chan struct{}
+	cancel      context.CancelFunc // 取消工作协程中正在进行的发送
+	finished    atomic.Int64       // 工作协程处理后得出最终结果的消息数
+	stopping    atomic.Bool
+	swept       atomic.Int64 // 上次清理历史记录的时间（UnixNano）
+	// dispatchMu 停止时等待正在进行的 Dispatch 结束后再关闭通道
+	dispatchMu sync.RWMutex
 }
 
-func New(bufferSize, workers int, sender *sender.Manager) *Dispatcher {
+// New 创建分发器，store 保存待发送的消息，deadLetters 保存最终发送失败的消息，
+// history 保存已完成消息的最终状态和幂等键，为空时仅保存在内存中
+func New(cfg config.DispatcherConfig, sender *sender.Manager, store, deadLetters, history queue.Store) *Dispatcher {
+	workers := cfg.WorkerPoolSize
 	if workers <= 0 {
 		workers = 2
 	}
+	bufferSize := cfg.BufferSize
 	if bufferSize <= 0 {
 		bufferSize = 50
 	}
+	if store == nil {
+		store = queue.NewMemoryStore()
+	}
+	if deadLetters == nil {
+		deadLetters = queue.NewMemoryStore()
+	}
+	if history == nil {
+		history = queue.NewMemoryStore()
+	}
+	overflow := cfg.Overflow
+	if overflow != OverflowBlock {
+		overflow = OverflowReject
+	}
+	timeout := cfg.EnqueueTimeout
+	if timeout <= 0 {
+		timeout = 3 * time.Second
+	}
+	starvation := cfg.Priority.StarvationLimit
+	if starvation <= 0 {
+		starvation = 10
+	}
+	drain := cfg.DrainTimeout
+	if drain <= 0 {
+		drain = 15 * time.Second
+	}
 
-	return &Dispatcher{
-		msgChan: make(chan *parser.Message, bufferSize),
-		sender:  sender,
-		workers: workers,
+	d := &Dispatcher{
+		lanes:       newLanes(bufferSize, cfg.Priority.Buffers),
+		sender:      sender,
+		store:       store,
+		deadLetters: deadLetters,
+		history:     history,
+		status:      newStatusTracker(cfg.StatusRetention),
+		idempotency: newIdempotencyCache(cfg.IdempotencyWindow),
+		retry:       newRetryPolicy(cfg.Retry),
+		defaultTTL:  cfg.DefaultTTL,
+		delayed:     newDelayQueue(),
+		overflow:    overflow,
+		timeout:     timeout,
+		starvation:  starvation,
+		drain:       drain,
+		workers:     workers,
+		quit:        make(chan struct{}),
+	}
+
+	// 恢复未发送和已完成消息的幂等键，重启后仍能识别去重窗口内的重复请求
+	now := time.Now()
+	d.sweepHistory(now)
+	d.pending = store.List()
+	for _, entries := range [][]*queue.Entry{history.List(), d.pending} {
+		for _, entry := range entries {
+			if d.idempotency.holds(entry, now) {
+				d.idempotency.reserve(entry.Message.IdempotencyKey, entry.ID, entry.EnqueuedAt)
+			}
+		}
 	}
+	return d
 }
 
 func (d *Dispatcher) Start(ctx context.Context) {
+	ctx, d.cancel = context.WithCancel(ctx)
+
 	for i := 0; i < d.workers; i++ {
 		d.wg.Add(1)
 		go d.worker(ctx)
 	}
+
+	// 等待退避的消息到期后重新投递
+	d.bg.Add(1)
+	go func() {
+		defer d.bg.Done()
+		d.delayed.run(d.quit, d.deliverDue)
+	}()
+
+	// 回放上次退出时未发送完成的消息
+	if pending := d.pending; len(pending) > 0 {
+		d.pending = nil
+		logger.Info("Replaying pending messages", zap.Int("count", len(pending)))
+		d.bg.Add(1)
+		go d.replayPending(ctx, pending)
+	}
+}
+
+// Stop 按顺序停止分发器：拒绝新消息，停止回放和延迟重试，
+// 在 drain_timeout 内发送完通道中剩余的消息，超时后中断发送。
+// 未发送的消息保留在持久化队列中，下次启动时继续发送。
+// 返回停止期间得出最终结果的消息数和仍留在队列中的消息数。
+func (d *Dispatcher) Stop() (flushed, saved int) {
+	if !d.stopping.CompareAndSwap(false, true) {
+		return 0, 0
+	}
+	close(d.quit)
+
+	// 等待正在进行的 Dispatch 返回，之后的调用都会看到 quit 已关闭
+	d.dispatchMu.Lock()
+	d.dispatchMu.Unlock()
+	d.bg.Wait()
+
+	queued := 0
+	for _, l := range d.lanes {
+		queued += len(l.ch)
+		close(l.ch)
+	}
+	before := d.finished.Load()
+	logger.Info("Draining message queue",
+		zap.Int("queued", queued),
+		zap.Duration("timeout", d.drain))
+
+	done := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(done)
+	}()
+
+	timer := time.NewTimer(d.drain)
+	defer timer.Stop()
+
+	select {
+	case <-done:
+	case <-timer.C:
+		logger.Warn("Drain timeout exceeded, interrupting in-flight sends")
+		if d.cancel != nil {
+			d.cancel()
+		}
+		<-done
+	}
+	if d.cancel != nil {
+		d.cancel()
+	}
+
+	// 被中断的发送仍保留在队列中，只计入 saved
+	flushed = int(d.finished.Load() - before)
+	saved = len(d.store.List())
+	if _, ok := d.store.(*queue.MemoryStore); ok && saved > 0 {
+		logger.Warn("Dispatcher stopped, queue persistence disabled, unsent messages lost",
+			zap.Int("flushed", flushed),
+			zap.Int("lost", saved))
+	} else {
+		logger.Info("Dispatcher stopped",
+			zap.Int("flushed", flushed),
+			zap.Int("saved", saved))
+	}
+	return flushed, saved
 }
 
-func (d *Dispatcher) Stop() {
-	close(d.msgChan)
-	d.wg.Wait()
+// Validate 校验消息能否被对应平台的发送器处理
+func (d *Dispatcher) Validate(msg *parser.Message) error {
+	return d.sender.Validate(msg)
 }
 
-func (d *Dispatcher) Dispatch(msg *parser.Message) {
+// Dispatch 为消息生成ID，写入持久化队列后投递给工作协程
+//
+// 工作通道已满时按配置立即拒绝或等待一段时间，仍无法入队则返回 ErrQueueFull。
+// 幂等键在去重窗口内重复出现时不再发送，返回携带原消息ID的 *DuplicateError。
+// 定时消息写入持久化队列后等待到期，不占用工作通道。
+// 分发器停止后返回 ErrStopped。
+func (d *Dispatcher) Dispatch(msg *parser.Message) error {
+	d.dispatchMu.RLock()
+	defer d.dispatchMu.RUnlock()
 	select {
-	case d.msgChan <- msg:
-		logger.Debug("Message dispatched",
-			zap.String("platform", string(msg.Platform)),
-			zap.String("content", msg.Content))
+	case <-d.quit:
+		return ErrStopped
 	default:
-		logger.Error("Message channel is full, message dropped",
+	}
+
+	now := time.Now()
+	msg.ID = queue.NewID()
+	msg.ResolveSchedule(now)
+	msg.ResolveExpiry(now, d.defaultTTL[string(msg.Platform)])
+	entry := &queue.Entry{
+		ID:         msg.ID,
+		Message:    msg,
+		EnqueuedAt: now,
+	}
+
+	key := msg.IdempotencyKey
+	if key != "" {
+		if id, ok := d.idempotency.reserve(key, entry.ID, entry.EnqueuedAt); !ok {
+			logger.Info("Duplicate message ignored",
+				zap.String("idempotency_key", key),
+				zap.String("id", id))
+			return &DuplicateError{ID: id}
+		}
+	}
+
+	if err := d.store.Put(entry); err != nil {
+		logger.Error("Failed to persist message",
+			zap.String("platform", string(msg.Platform)),
+			zap.Error(err))
+		if key != "" {
+			d.idempotency.release(key, entry.ID)
+		}
+		return fmt.Errorf("persist message failed: %w", err)
+	}
+
+	if isScheduled(entry, now) {
+		d.schedule(entry)
+		logger.Info("Message scheduled",
+			zap.String("id", entry.ID),
 			zap.String("platform", string(msg.Platform)),
-			zap.String("content", msg.Content))
+			zap.Time("send_at", *msg.SendAt))
+		return nil
+	}
+	d.status.update(entry, StateQueued)
+
+	l := d.laneFor(msg)
+	if !d.offer(l, entry) {
+		d.reject(l, entry)
+		select {
+		case <-d.quit:
+			return ErrStopped
+		default:
+			return ErrQueueFull
+		}
+	}
+
+	if l.saturated.CompareAndSwap(true, false) {
+		logger.Info("Message queue recovered from saturation",
+			zap.String("priority", string(l.priority)),
+			zap.Int64("rejected", l.rejected.Swap(0)))
 	}
+	logger.Debug("Message dispatched",
+		zap.String("id", entry.ID),
+		zap.String("platform", string(msg.Platform)),
+		zap.String("priority", string(l.priority)),
+		zap.String("content", msg.Content))
+	return nil
+}
+
+// offer 按溢出策略尝试将消息放入对应优先级的通道
+func (d *Dispatcher) offer(l *lane, entry *queue.Entry) bool {
+	select {
+	case l.ch <- entry:
+		return true
+	default:
+	}
+	if d.overflow != OverflowBlock {
+		return false
+	}
+
+	timer := time.NewTimer(d.timeout)
+	defer timer.Stop()
+
+	select {
+	case l.ch <- entry:
+		return true
+	case <-timer.C:
+		return false
+	case <-d.quit:
+		return false
+	}
+}
+
+// reject 撤销未能入队的消息，并记录队列饱和事件
+func (d *Dispatcher) reject(l *lane, entry *queue.Entry) {
+	d.remove(entry)
+	d.status.forget(entry.ID)
+	if key := entry.Message.IdempotencyKey; key != "" {
+		d.idempotency.release(key, entry.ID)
+	}
+	l.rejected.Add(1)
+
+	if l.saturated.CompareAndSwap(false, true) {
+		logger.Error("Message queue saturated, rejecting new messages",
+			zap.String("priority", string(l.priority)),
+			zap.Int("capacity", cap(l.ch)),
+			zap.String("overflow", d.overflow))
+	}
+	logger.Warn("Message rejected, queue is full",
+		zap.String("id", entry.ID),
+		zap.String("platform", string(entry.Message.Platform)),
+		zap.String("priority", string(l.priority)),
+		zap.Int("queued", len(l.ch)))
+}
+
+// replayPending 将持久化队列中遗留的消息重新投递，通道满时阻塞等待，
+// 尚未到期的定时消息重新放入延迟队列
+func (d *Dispatcher) replayPending(ctx context.Context, entries []*queue.Entry) {
+	defer d.bg.Done()
+
+	now := time.Now()
+	for _, entry := range entries {
+		if isScheduled(entry, now) {
+			d.schedule(entry)
+			continue
+		}
+		if !d.enqueue(ctx, entry) {
+			return
+		}
+	}
+}
+
+// enqueue 阻塞投递消息到对应优先级的通道，分发器停止时返回 false
+func (d *Dispatcher) enqueue(ctx context.Context, entry *queue.Entry) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case <-d.quit:
+		return false
+	case d.laneFor(entry.Message).ch <- entry:
+		return true
+	}
+}
+
+// deliverDue 将到期的重试或定时消息非阻塞地放入对应优先级的通道，
+// 通道已满时等待 dueRetryInterval 后再试，避免某个优先级的积压挡住其他优先级到期的消息
+func (d *Dispatcher) deliverDue(entry *queue.Entry) bool {
+	select {
+	case d.laneFor(entry.Message).ch <- entry:
+	default:
+		d.delayed.push(entry, time.Now().Add(dueRetryInterval))
+	}
+	return true
 }
 
 func (d *Dispatcher) worker(ctx context.Context) {
 	defer d.wg.Done()
 
+	chans := make([]chan *queue.Entry, len(d.lanes))
+	for i, l := range d.lanes {
+		chans[i] = l.ch
+	}
+
+	skipped := make([]int, len(chans))
 	for {
-		select {
-		case <-ctx.Done():
+		entry, ok := d.next(ctx, chans, skipped)
+		if !ok {
 			return
-		case msg, ok := <-d.msgChan:
-			if !ok {
-				return
-			}
-			if err := d.sender.Send(ctx, msg); err != nil {
-				logger.Error("Failed to send message",
-					zap.String("platform", string(msg.Platform)),
-					zap.Error(err))
-				continue
-			}
-			logger.Info("Message sent successfully",
-				zap.String("platform", string(msg.Platform)))
 		}
+		if d.process(ctx, entry) {
+			d.finished.Add(1)
+		}
+	}
+}
+
+// process 发送一条消息，失败时按重试策略决定退避重试还是放弃
+//
+// 消息得出最终结果（发送成功、部分送达、进入死信或过期）时返回 true，等待重试或因关闭被中断时返回 false。
+func (d *Dispatcher) process(ctx context.Context, entry *queue.Entry) bool {
+	msg := entry.Message
+	start := time.Now()
+	if msg.Expired(start) {
+		d.expire(entry)
+		return true
+	}
+
+	senderName := d.sender.Name(msg)
+	d.status.update(entry, StateSending)
+
+	sendCtx, receipt := sender.WithReceipt(ctx)
+	err := d.sender.Send(sendCtx, msg)
+	if err == nil {
+		entry.Attempts = append(entry.Attempts, queue.Attempt{At: start, Sender: senderName, Receipts: receipt.IDs()})
+		d.finish(entry, StateSent)
+		d.remove(entry)
+		logger.Info("Message sent successfully",
+			zap.String("id", entry.ID),
+			zap.String("platform", string(msg.Platform)))
+		return true
+	}
+
+	var partial *sender.PartialError
+	if errors.As(err, &partial) && partial.RetryExtra == nil {
+		entry.Attempts = append(entry.Attempts, queue.Attempt{At: start, Sender: senderName, Error: err.Error(), Receipts: receipt.IDs()})
+		d.finish(entry, StatePartial)
+		d.remove(entry)
+		logger.Warn("Message partially delivered",
+			zap.String("id", entry.ID),
+			zap.String("platform", string(msg.Platform)),
+			zap.Strings("failed", partial.Failed))
+		return true
+	}
+
+	if ctx.Err() != nil {
+		// 分发器正在关闭，消息保留在持久化队列中，下次启动时重新发送
+		logger.Warn("Message send interrupted by shutdown",
+			zap.String("id", entry.ID),
+			zap.Error(err))
+		d.status.update(entry, StateQueued)
+		return false
+	}
+
+	entry.Attempts = append(entry.Attempts, queue.Attempt{At: start, Sender: senderName, Error: err.Error(), Receipts: receipt.IDs()})
+	if partial != nil {
+		// 已送达的接收人不再重试，复制消息避免修改调用方持有的原始消息
+		retryMsg := *msg
+		retryMsg.Extra = partial.RetryExtra
+		entry.Message = &retryMsg
+	}
+
+	delay, ok := d.retry.next(entry, err)
+	if !ok {
+		logger.Error("Failed to send message, moving to dead letters",
+			zap.String("id", entry.ID),
+			zap.String("platform", string(msg.Platform)),
+			zap.Int("attempts", len(entry.Attempts)),
+			zap.Bool("retryable", sender.IsRetryable(err)),
+			zap.Error(err))
+		d.bury(entry)
+		return true
+	}
+
+	// 下次重试时已过期，无需再等待
+	if msg.Expired(time.Now().Add(delay)) {
+		d.expire(entry)
+		return true
+	}
+
+	// 记录尝试次数，重启后继续累计
+	if err := d.store.Put(entry); err != nil {
+		logger.Error("Failed to persist message attempts",
+			zap.String("id", entry.ID),
+			zap.Error(err))
+	}
+
+	d.status.update(entry, StateRetrying)
+	logger.Warn("Failed to send message, will retry",
+		zap.String("id", entry.ID),
+		zap.String("platform", string(msg.Platform)),
+		zap.Int("attempts", len(entry.Attempts)),
+		zap.Duration("backoff", delay),
+		zap.Error(err))
+	d.delayed.push(entry, time.Now().Add(delay))
+	return false
+}
+
+// expire 丢弃已过期的消息，记录为 expired 状态
+func (d *Dispatcher) expire(entry *queue.Entry) {
+	d.finish(entry, StateExpired)
+	d.remove(entry)
+	logger.Warn("Message expired, discarded",
+		zap.String("id", entry.ID),
+		zap.String("platform", string(entry.Message.Platform)),
+		zap.Time("expires_at", *entry.Message.ExpiresAt),
+		zap.Int("attempts", len(entry.Attempts)))
+}
+
+// bury 将消息移入死信队列
+func (d *Dispatcher) bury(entry *queue.Entry) {
+	d.finish(entry, StateFailed)
+	if err := d.deadLetters.Put(entry); err != nil {
+		// 写入死信失败时保留在待发送队列中，下次启动时重新发送，避免丢失
+		logger.Error("Failed to persist dead letter",
+			zap.String("id", entry.ID),
+			zap.Error(err))
+		return
+	}
+	d.remove(entry)
+}
+
+// remove 从持久化队列中删除消息
+func (d *Dispatcher) remove(entry *queue.Entry) {
+	if err := d.store.Delete(entry.ID); err != nil {
+		logger.Error("Failed to remove message from queue",
+			zap.String("id", entry.ID),
+			zap.Error(err))
 	}
 }
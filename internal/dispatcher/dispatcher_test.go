@@ -0,0 +1,717 @@
+package dispatcher
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"notify/internal/config"
+	"notify/internal/parser"
+	"notify/internal/queue"
+	"notify/internal/sender"
+)
+
+// fakeSender 按预设的错误序列返回结果
+type fakeSender struct {
+	mu     sync.Mutex
+	errs   []error
+	calls  int
+	extras []map[string]any
+	sent   chan string
+}
+
+func newFakeSender(errs ...error) *fakeSender {
+	return &fakeSender{errs: errs, sent: make(chan string, 100)}
+}
+
+func (s *fakeSender) Send(ctx context.Context, content string, summary string, extra map[string]any) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	call := s.calls
+	s.calls++
+	s.extras = append(s.extras, extra)
+	if call < len(s.errs) && s.errs[call] != nil {
+		return s.errs[call]
+	}
+	s.sent <- content
+	return nil
+}
+
+func (s *fakeSender) callCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls
+}
+
+func newTestDispatcher(fake *fakeSender, store queue.Store) *Dispatcher {
+	mgr := sender.NewManager()
+	mgr.Register(parser.PlatformDingTalk, fake)
+	return New(config.DispatcherConfig{
+		BufferSize:     10,
+		WorkerPoolSize: 1,
+		Retry: config.RetryConfig{
+			MaxAttempts:    3,
+			InitialBackoff: 10 * time.Millisecond,
+			MaxBackoff:     50 * time.Millisecond,
+		},
+	}, mgr, store, nil, nil)
+}
+
+func waitSent(t *testing.T, fake *fakeSender, want string) {
+	t.Helper()
+	select {
+	case got := <-fake.sent:
+		if got != want {
+			t.Errorf("Expected content '%s', got '%s'", want, got)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timed out waiting for message to be sent")
+	}
+}
+
+// waitState 轮询等待消息进入指定状态
+func waitState(t *testing.T, disp *Dispatcher, id string, want State) *Status {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		status, ok := disp.Status(id)
+		if ok && status.State == want {
+			return status
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for state %s, got %+v", want, status)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestDispatcherRetry(t *testing.T) {
+	tests := []struct {
+		name      string
+		errs      []error
+		wantCalls int
+		wantSent  bool
+	}{
+		{
+			name:      "retryable error then success",
+			errs:      []error{sender.Retryable(errors.New("timeout")), sender.Retryable(errors.New("timeout"))},
+			wantCalls: 3,
+			wantSent:  true,
+		},
+		{
+			name:      "permanent error",
+			errs:      []error{sender.Permanent(errors.New("invalid user"))},
+			wantCalls: 1,
+		},
+		{
+			name:      "attempts exhausted",
+			errs:      []error{errors.New("boom"), errors.New("boom"), errors.New("boom"), errors.New("boom")},
+			wantCalls: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeSender(tt.errs...)
+			store := queue.NewMemoryStore()
+			disp := newTestDispatcher(fake, store)
+			disp.Start(context.Background())
+			defer disp.Stop()
+
+			msg := &parser.Message{Platform: parser.PlatformDingTalk, Content: "hello"}
+			if err := disp.Dispatch(msg); err != nil {
+				t.Fatal(err)
+			}
+			if msg.ID == "" {
+				t.Fatal("Expected message ID to be assigned")
+			}
+
+			wantState := StateFailed
+			if tt.wantSent {
+				waitSent(t, fake, "hello")
+				wantState = StateSent
+			}
+			status := waitState(t, disp, msg.ID, wantState)
+			if len(status.Attempts) != tt.wantCalls {
+				t.Errorf("Expected %d attempts in status, got %d", tt.wantCalls, len(status.Attempts))
+			}
+
+			if calls := fake.callCount(); calls != tt.wantCalls {
+				t.Errorf("Expected %d send calls, got %d", tt.wantCalls, calls)
+			}
+			if n := len(store.List()); n != 0 {
+				t.Errorf("Expected queue to be empty, got %d entries", n)
+			}
+
+			wantDead := 0
+			if !tt.wantSent {
+				wantDead = 1
+			}
+			dead := disp.DeadLetters()
+			if len(dead) != wantDead {
+				t.Fatalf("Expected %d dead letters, got %d", wantDead, len(dead))
+			}
+			if wantDead > 0 && len(dead[0].Attempts) != tt.wantCalls {
+				t.Errorf("Expected %d recorded attempts, got %d", tt.wantCalls, len(dead[0].Attempts))
+			}
+		})
+	}
+}
+
+func TestRetryPolicyRetryAfter(t *testing.T) {
+	policy := newRetryPolicy(config.RetryConfig{
+		MaxAttempts:    3,
+		InitialBackoff: 10 * time.Millisecond,
+		MaxBackoff:     50 * time.Millisecond,
+	})
+	entry := &queue.Entry{EnqueuedAt: time.Now(), Attempts: []queue.Attempt{{}}}
+
+	// 平台要求的重试间隔超过 maxBackoff 时仍以平台要求为准
+	delay, ok := policy.next(entry, sender.RetryAfter(errors.New("rate limited"), time.Second))
+	if !ok || delay != time.Second {
+		t.Errorf("Expected retry after 1s, got %v (retry=%t)", delay, ok)
+	}
+
+	delay, ok = policy.next(entry, sender.RetryAfter(errors.New("rate limited"), time.Millisecond))
+	if !ok || delay < 8*time.Millisecond {
+		t.Errorf("Expected backoff to win over shorter hint, got %v (retry=%t)", delay, ok)
+	}
+}
+
+func TestRetryPolicyMaxElapsed(t *testing.T) {
+	policy := newRetryPolicy(config.RetryConfig{
+		MaxAttempts:    3,
+		InitialBackoff: 10 * time.Millisecond,
+		MaxElapsed:     time.Hour,
+	})
+	now := time.Now()
+	sendAt := now.Add(-time.Minute)
+	err := sender.Retryable(errors.New("connection reset"))
+
+	tests := []struct {
+		name  string
+		entry *queue.Entry
+		want  bool
+	}{
+		{
+			// 提交后已超过 max_elapsed，不再重试
+			name:  "immediate message",
+			entry: &queue.Entry{EnqueuedAt: now.Add(-2 * time.Hour), Message: &parser.Message{}, Attempts: []queue.Attempt{{}}},
+			want:  false,
+		},
+		{
+			// 提前 2 小时提交的定时消息，从计划发送时间开始计算
+			name:  "scheduled message",
+			entry: &queue.Entry{EnqueuedAt: now.Add(-2 * time.Hour), Message: &parser.Message{SendAt: &sendAt}, Attempts: []queue.Attempt{{}}},
+			want:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, ok := policy.next(tt.entry, err); ok != tt.want {
+				t.Errorf("Expected retry=%t, got %t", tt.want, ok)
+			}
+		})
+	}
+}
+
+func TestDispatcherPartial(t *testing.T) {
+	fake := newFakeSender(sender.Partial([]string{"user:lisi"}))
+	store := queue.NewMemoryStore()
+	disp := newTestDispatcher(fake, store)
+	disp.Start(context.Background())
+	defer disp.Stop()
+
+	msg := &parser.Message{Platform: parser.PlatformDingTalk, Content: "hello"}
+	if err := disp.Dispatch(msg); err != nil {
+		t.Fatal(err)
+	}
+
+	// 部分送达不重试，也不进入死信队列
+	status := waitState(t, disp, msg.ID, StatePartial)
+	if len(status.Attempts) != 1 || status.Error == "" {
+		t.Errorf("Expected 1 attempt with error, got %+v", status.Attempts)
+	}
+	if calls := fake.callCount(); calls != 1 {
+		t.Errorf("Expected 1 send call, got %d", calls)
+	}
+	if n := len(store.List()); n != 0 {
+		t.Errorf("Expected queue to be empty, got %d entries", n)
+	}
+	if n := len(disp.DeadLetters()); n != 0 {
+		t.Errorf("Expected no dead letters, got %d", n)
+	}
+}
+
+func TestDispatcherPartialRetry(t *testing.T) {
+	retryExtra := map[string]any{"uids": []any{"UID_b"}}
+	fake := newFakeSender(sender.PartialRetry([]string{"uid:UID_b"}, retryExtra))
+	disp := newTestDispatcher(fake, nil)
+	disp.Start(context.Background())
+	defer disp.Stop()
+
+	msg := &parser.Message{
+		Platform: parser.PlatformDingTalk,
+		Content:  "hello",
+		Extra:    map[string]any{"uids": []any{"UID_a", "UID_b"}},
+	}
+	if err := disp.Dispatch(msg); err != nil {
+		t.Fatal(err)
+	}
+
+	// 重试时只发送给失败的接收人
+	waitSent(t, fake, "hello")
+	waitState(t, disp, msg.ID, StateSent)
+
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	if len(fake.extras) != 2 {
+		t.Fatalf("Expected 2 send calls, got %d", len(fake.extras))
+	}
+	if uids := fake.extras[1]["uids"].([]any); len(uids) != 1 || uids[0] != "UID_b" {
+		t.Errorf("Expected retry to UID_b only, got %v", fake.extras[1]["uids"])
+	}
+}
+
+func TestDeadLetterReplay(t *testing.T) {
+	fake := newFakeSender(sender.Permanent(errors.New("invalid payload")))
+	disp := newTestDispatcher(fake, nil)
+	disp.Start(context.Background())
+	defer disp.Stop()
+
+	if err := disp.Dispatch(&parser.Message{Platform: parser.PlatformDingTalk, Content: "retry me"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// 等待消息进入死信队列
+	deadline := time.Now().Add(2 * time.Second)
+	for len(disp.DeadLetters()) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for dead letter")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	id := disp.DeadLetters()[0].ID
+	if err := disp.ReplayDeadLetter(id); err != nil {
+		t.Fatal(err)
+	}
+	waitSent(t, fake, "retry me")
+
+	if n := len(disp.DeadLetters()); n != 0 {
+		t.Errorf("Expected dead letters to be empty after replay, got %d", n)
+	}
+	if err := disp.ReplayDeadLetter(id); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestDispatcherReplay(t *testing.T) {
+	store := queue.NewMemoryStore()
+	store.Put(&queue.Entry{
+		ID:         queue.NewID(),
+		Message:    &parser.Message{Platform: parser.PlatformDingTalk, Content: "left over"},
+		EnqueuedAt: time.Now(),
+	})
+
+	fake := newFakeSender()
+	disp := newTestDispatcher(fake, store)
+	disp.Start(context.Background())
+	defer disp.Stop()
+
+	waitSent(t, fake, "left over")
+}
+
+func TestDispatcherHistory(t *testing.T) {
+	dir := t.TempDir()
+	open := func(fake *fakeSender) (*Dispatcher, queue.Store) {
+		history, err := queue.Open(dir, "history")
+		if err != nil {
+			t.Fatal(err)
+		}
+		mgr := sender.NewManager()
+		mgr.Register(parser.PlatformDingTalk, fake)
+		return New(config.DispatcherConfig{BufferSize: 10, WorkerPoolSize: 1}, mgr, nil, nil, history), history
+	}
+
+	fake := newFakeSender()
+	disp, history := open(fake)
+	disp.Start(context.Background())
+	msg := &parser.Message{Platform: parser.PlatformDingTalk, Content: "hello"}
+	if err := disp.Dispatch(msg); err != nil {
+		t.Fatal(err)
+	}
+	waitState(t, disp, msg.ID, StateSent)
+	disp.Stop()
+
+	// 超过保留时间的记录在打开时清理
+	finishedAt := time.Now().Add(-48 * time.Hour)
+	history.Put(&queue.Entry{
+		ID:         "stale",
+		Message:    &parser.Message{Platform: parser.PlatformDingTalk},
+		EnqueuedAt: finishedAt,
+		State:      string(StateSent),
+		FinishedAt: &finishedAt,
+	})
+	history.Close()
+
+	// 重启后内存中的状态丢失，仍能从历史记录中查到最终状态
+	disp, history = open(newFakeSender())
+	defer history.Close()
+	status, ok := disp.Status(msg.ID)
+	if !ok || status.State != StateSent || len(status.Attempts) != 1 {
+		t.Fatalf("Expected sent status with 1 attempt after restart, got %+v", status)
+	}
+	if _, ok := disp.Status("stale"); ok {
+		t.Error("Expected stale status to be swept")
+	}
+	if _, ok := history.Get("stale"); ok {
+		t.Error("Expected stale record to be removed from history")
+	}
+}
+
+func TestDispatcherIdempotencyRestart(t *testing.T) {
+	history := queue.NewMemoryStore()
+	newDispatcher := func(fake *fakeSender) *Dispatcher {
+		mgr := sender.NewManager()
+		mgr.Register(parser.PlatformDingTalk, fake)
+		return New(config.DispatcherConfig{BufferSize: 10, WorkerPoolSize: 1}, mgr, nil, nil, history)
+	}
+
+	disp := newDispatcher(newFakeSender())
+	disp.Start(context.Background())
+	msg := &parser.Message{Platform: parser.PlatformDingTalk, Content: "deploy", IdempotencyKey: "deploy-42"}
+	if err := disp.Dispatch(msg); err != nil {
+		t.Fatal(err)
+	}
+	waitState(t, disp, msg.ID, StateSent)
+	disp.Stop()
+
+	// 重启后已发送消息的幂等键仍在去重窗口内
+	fake := newFakeSender()
+	disp = newDispatcher(fake)
+	disp.Start(context.Background())
+	defer disp.Stop()
+
+	var dup *DuplicateError
+	err := disp.Dispatch(&parser.Message{Platform: parser.PlatformDingTalk, Content: "deploy", IdempotencyKey: "deploy-42"})
+	if !errors.As(err, &dup) || dup.ID != msg.ID {
+		t.Fatalf("Expected duplicate of %s, got %v", msg.ID, err)
+	}
+	if calls := fake.callCount(); calls != 0 {
+		t.Errorf("Expected no send calls after restart, got %d", calls)
+	}
+}
+
+func TestDispatchQueueFull(t *testing.T) {
+	tests := []struct {
+		name     string
+		overflow string
+		minWait  time.Duration
+	}{
+		{name: "reject", overflow: OverflowReject},
+		{name: "block with timeout", overflow: OverflowBlock, minWait: 50 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := queue.NewMemoryStore()
+			// 不启动工作协程，缓冲区写满后即饱和
+			disp := New(config.DispatcherConfig{
+				BufferSize:     1,
+				Overflow:       tt.overflow,
+				EnqueueTimeout: 50 * time.Millisecond,
+			}, sender.NewManager(), store, nil, nil)
+
+			if err := disp.Dispatch(&parser.Message{Platform: parser.PlatformDingTalk, Content: "first"}); err != nil {
+				t.Fatal(err)
+			}
+
+			msg := &parser.Message{Platform: parser.PlatformDingTalk, Content: "second"}
+			start := time.Now()
+			err := disp.Dispatch(msg)
+			if !errors.Is(err, ErrQueueFull) {
+				t.Fatalf("Expected ErrQueueFull, got %v", err)
+			}
+			if waited := time.Since(start); waited < tt.minWait {
+				t.Errorf("Expected to wait at least %s, waited %s", tt.minWait, waited)
+			}
+
+			// 被拒绝的消息不应留在持久化队列和状态记录中
+			if n := len(store.List()); n != 1 {
+				t.Errorf("Expected 1 persisted message, got %d", n)
+			}
+			if _, ok := disp.Status(msg.ID); ok {
+				t.Error("Expected rejected message to have no status")
+			}
+		})
+	}
+}
+
+func TestDispatcherSchedule(t *testing.T) {
+	fake := newFakeSender()
+	store := queue.NewMemoryStore()
+
+	// 模拟重启前已持久化的定时消息
+	later := time.Now().Add(time.Hour)
+	store.Put(&queue.Entry{
+		ID:         "persisted",
+		Message:    &parser.Message{Platform: parser.PlatformDingTalk, Content: "tomorrow", SendAt: &later},
+		EnqueuedAt: time.Now(),
+	})
+
+	disp := newTestDispatcher(fake, store)
+	disp.Start(context.Background())
+	defer disp.Stop()
+
+	soon := &parser.Message{Platform: parser.PlatformDingTalk, Content: "soon", Delay: "50ms"}
+	if err := disp.Dispatch(soon); err != nil {
+		t.Fatal(err)
+	}
+	if soon.SendAt == nil || soon.Delay != "" {
+		t.Fatalf("Expected delay to be resolved into send_at, got %+v", soon)
+	}
+	if status, _ := disp.Status(soon.ID); status.State != StateScheduled {
+		t.Errorf("Expected state %s, got %s", StateScheduled, status.State)
+	}
+	waitSent(t, fake, "soon")
+
+	waitState(t, disp, "persisted", StateScheduled)
+	if n := len(disp.Scheduled()); n != 1 {
+		t.Fatalf("Expected 1 scheduled message, got %d", n)
+	}
+	if err := disp.Cancel("persisted"); err != nil {
+		t.Fatal(err)
+	}
+	if status, _ := disp.Status("persisted"); status.State != StateCancelled {
+		t.Errorf("Expected state %s, got %s", StateCancelled, status.State)
+	}
+	if n := len(disp.Scheduled()); n != 0 {
+		t.Errorf("Expected no scheduled messages, got %d", n)
+	}
+	if err := disp.Cancel("persisted"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestDispatcherExpiry(t *testing.T) {
+	fake := newFakeSender()
+	store := queue.NewMemoryStore()
+
+	// 积压期间已过期的消息
+	past := time.Now().Add(-time.Minute)
+	store.Put(&queue.Entry{
+		ID:         "stale",
+		Message:    &parser.Message{Platform: parser.PlatformDingTalk, Content: "stale alert", ExpiresAt: &past},
+		EnqueuedAt: time.Now().Add(-time.Hour),
+	})
+
+	disp := newTestDispatcher(fake, store)
+	disp.defaultTTL = map[string]time.Duration{string(parser.PlatformDingTalk): time.Hour}
+	disp.Start(context.Background())
+	defer disp.Stop()
+
+	waitState(t, disp, "stale", StateExpired)
+	if _, ok := store.Get("stale"); ok {
+		t.Error("Expected expired message to be removed from queue")
+	}
+
+	// 未指定有效期时使用平台默认值
+	msg := &parser.Message{Platform: parser.PlatformDingTalk, Content: "fresh"}
+	if err := disp.Dispatch(msg); err != nil {
+		t.Fatal(err)
+	}
+	if msg.ExpiresAt == nil || time.Until(*msg.ExpiresAt) < 59*time.Minute {
+		t.Errorf("Expected default ttl to be applied, got %v", msg.ExpiresAt)
+	}
+	waitSent(t, fake, "fresh")
+
+	if calls := fake.callCount(); calls != 1 {
+		t.Errorf("Expected only the fresh message to be sent, got %d calls", calls)
+	}
+}
+
+// TestNextBlocksOnAllLanes 通道为空时阻塞等待全部通道，不依赖优先级的数量
+func TestNextBlocksOnAllLanes(t *testing.T) {
+	d := &Dispatcher{starvation: 10}
+	chans := make([]chan *queue.Entry, 4)
+	for i := range chans {
+		chans[i] = make(chan *queue.Entry, 1)
+	}
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		chans[3] <- &queue.Entry{ID: "last"}
+	}()
+
+	skipped := make([]int, len(chans))
+	entry, ok := d.next(context.Background(), chans, skipped)
+	if !ok || entry.ID != "last" {
+		t.Fatalf("Expected entry from the last lane, got %+v", entry)
+	}
+
+	for _, ch := range chans {
+		close(ch)
+	}
+	if _, ok := d.next(context.Background(), chans, skipped); ok {
+		t.Errorf("Expected next to return false once all lanes are closed")
+	}
+}
+
+func TestDeliverDueFullLane(t *testing.T) {
+	disp := New(config.DispatcherConfig{
+		BufferSize: 10,
+		Priority:   config.PriorityConfig{Buffers: map[string]int{"low": 1}},
+	}, sender.NewManager(), nil, nil, nil)
+	defer close(disp.quit)
+
+	// 低优先级通道已满，到期的低优先级消息不应挡住高优先级消息
+	low := disp.laneFor(&parser.Message{Priority: parser.PriorityLow})
+	high := disp.laneFor(&parser.Message{Priority: parser.PriorityHigh})
+	low.ch <- &queue.Entry{ID: "backlog", Message: &parser.Message{Priority: parser.PriorityLow}}
+	now := time.Now()
+	disp.delayed.push(&queue.Entry{ID: "low", Message: &parser.Message{Priority: parser.PriorityLow}}, now)
+	disp.delayed.push(&queue.Entry{ID: "high", Message: &parser.Message{Priority: parser.PriorityHigh}}, now.Add(time.Millisecond))
+	go disp.delayed.run(disp.quit, disp.deliverDue)
+
+	select {
+	case entry := <-high.ch:
+		if entry.ID != "high" {
+			t.Fatalf("Expected 'high', got '%s'", entry.ID)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("High priority message blocked by full low priority lane")
+	}
+
+	// 低优先级通道腾出空间后，等待的消息再次投递
+	<-low.ch
+	select {
+	case entry := <-low.ch:
+		if entry.ID != "low" {
+			t.Fatalf("Expected 'low', got '%s'", entry.ID)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for low priority message to be redelivered")
+	}
+}
+
+func TestDispatcherPriority(t *testing.T) {
+	tests := []struct {
+		name    string
+		backlog map[parser.Priority]int
+		want    []string
+	}{
+		{
+			// 连续处理 3 条高优先级消息后强制处理一条低优先级消息
+			name:    "high and low",
+			backlog: map[parser.Priority]int{parser.PriorityHigh: 5, parser.PriorityLow: 5},
+			want:    []string{"high", "high", "high", "low", "high", "high", "low", "low", "low", "low"},
+		},
+		{
+			// 三个通道都积压时，普通和低优先级通道各自计数，依次轮到
+			name:    "all lanes",
+			backlog: map[parser.Priority]int{parser.PriorityHigh: 6, parser.PriorityNormal: 2, parser.PriorityLow: 2},
+			want:    []string{"high", "high", "high", "normal", "low", "high", "high", "high", "normal", "low"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeSender()
+			mgr := sender.NewManager()
+			mgr.Register(parser.PlatformDingTalk, fake)
+			disp := New(config.DispatcherConfig{
+				BufferSize:     10,
+				WorkerPoolSize: 1,
+				Priority:       config.PriorityConfig{StarvationLimit: 3},
+			}, mgr, nil, nil, nil)
+
+			// 先积压消息再启动工作协程，低优先级消息先入队
+			for i := len(parser.Priorities) - 1; i >= 0; i-- {
+				priority := parser.Priorities[i]
+				for j := 0; j < tt.backlog[priority]; j++ {
+					if err := disp.Dispatch(&parser.Message{Platform: parser.PlatformDingTalk, Content: string(priority), Priority: priority}); err != nil {
+						t.Fatal(err)
+					}
+				}
+			}
+
+			disp.Start(context.Background())
+			defer disp.Stop()
+
+			for i, content := range tt.want {
+				select {
+				case got := <-fake.sent:
+					if got != content {
+						t.Fatalf("Message %d: expected '%s', got '%s'", i, content, got)
+					}
+				case <-time.After(2 * time.Second):
+					t.Fatal("Timed out waiting for message to be sent")
+				}
+			}
+		})
+	}
+}
+
+// blockingSender 阻塞直到 ctx 结束，模拟卡住的平台接口
+type blockingSender struct{}
+
+func (blockingSender) Send(ctx context.Context, content string, summary string, extra map[string]any) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestDispatcherStop(t *testing.T) {
+	t.Run("drain queued messages", func(t *testing.T) {
+		fake := newFakeSender()
+		store := queue.NewMemoryStore()
+		disp := newTestDispatcher(fake, store)
+		for i := 0; i < 3; i++ {
+			if err := disp.Dispatch(&parser.Message{Platform: parser.PlatformDingTalk, Content: "queued"}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		disp.Start(context.Background())
+
+		// 工作协程可能在 Stop 之前就已发送完，flushed 不固定，以发送次数和队列为准
+		_, saved := disp.Stop()
+		if calls := fake.callCount(); calls != 3 || saved != 0 {
+			t.Errorf("Expected 3 sent and 0 saved, got %d sent and %d saved", calls, saved)
+		}
+		if n := len(store.List()); n != 0 {
+			t.Errorf("Expected queue to be empty, got %d entries", n)
+		}
+		if err := disp.Dispatch(&parser.Message{Platform: parser.PlatformDingTalk, Content: "late"}); !errors.Is(err, ErrStopped) {
+			t.Errorf("Expected ErrStopped, got %v", err)
+		}
+	})
+
+	t.Run("keep unsent messages after drain timeout", func(t *testing.T) {
+		mgr := sender.NewManager()
+		mgr.Register(parser.PlatformDingTalk, blockingSender{})
+		store := queue.NewMemoryStore()
+		disp := New(config.DispatcherConfig{
+			BufferSize:     10,
+			WorkerPoolSize: 1,
+			DrainTimeout:   50 * time.Millisecond,
+		}, mgr, store, nil, nil)
+		for i := 0; i < 3; i++ {
+			if err := disp.Dispatch(&parser.Message{Platform: parser.PlatformDingTalk, Content: "stuck"}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		disp.Start(context.Background())
+
+		// 被中断的发送不计入 flushed
+		flushed, saved := disp.Stop()
+		if flushed != 0 || saved != 3 {
+			t.Errorf("Expected 0 flushed and 3 saved, got %d flushed and %d saved", flushed, saved)
+		}
+		if n := len(store.List()); n != 3 {
+			t.Errorf("Expected 3 messages left in queue, got %d", n)
+		}
+	})
+}
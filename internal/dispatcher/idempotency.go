@@ -0,0 +1,79 @@
+package dispatcher
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"notify/internal/queue"
+)
+
+// DuplicateError 幂等键在去重窗口内已被使用，ID 为首次请求的消息ID
+type DuplicateError struct {
+	ID string
+}
+
+func (e *DuplicateError) Error() string {
+	return fmt.Sprintf("duplicate request, original message: %s", e.ID)
+}
+
+type idempotencyRecord struct {
+	id        string
+	expiresAt time.Time
+}
+
+// idempotencyCache 记录去重窗口内使用过的幂等键
+type idempotencyCache struct {
+	mu        sync.Mutex
+	records   map[string]idempotencyRecord
+	window    time.Duration
+	lastSweep time.Time
+}
+
+func newIdempotencyCache(window time.Duration) *idempotencyCache {
+	if window <= 0 {
+		window = 24 * time.Hour
+	}
+	return &idempotencyCache{
+		records:   make(map[string]idempotencyRecord),
+		window:    window,
+		lastSweep: time.Now(),
+	}
+}
+
+// reserve 为幂等键登记消息ID，键已存在且未过期时返回已登记的ID
+func (c *idempotencyCache) reserve(key, id string, at time.Time) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(c.lastSweep) >= sweepInterval {
+		for k, record := range c.records {
+			if now.After(record.expiresAt) {
+				delete(c.records, k)
+			}
+		}
+		c.lastSweep = now
+	}
+
+	if record, ok := c.records[key]; ok && now.Before(record.expiresAt) {
+		return record.id, false
+	}
+	c.records[key] = idempotencyRecord{id: id, expiresAt: at.Add(c.window)}
+	return id, true
+}
+
+// release 释放未能成功入队的幂等键，允许客户端重试
+func (c *idempotencyCache) release(key, id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if record, ok := c.records[key]; ok && record.id == id {
+		delete(c.records, key)
+	}
+}
+
+// holds 判断记录的幂等键是否仍在去重窗口内
+func (c *idempotencyCache) holds(entry *queue.Entry, now time.Time) bool {
+	return entry.Message.IdempotencyKey != "" && now.Before(entry.EnqueuedAt.Add(c.window))
+}
@@ -0,0 +1,130 @@
+package dispatcher
+
+import (
+	"context"
+	"reflect"
+	"sync/atomic"
+
+	"notify/internal/parser"
+	"notify/internal/queue"
+)
+
+// lane 单个优先级的消息通道，队列满的处理按优先级相互独立
+type lane struct {
+	priority  parser.Priority
+	ch        chan *queue.Entry
+	saturated atomic.Bool  // 通道是否处于饱和状态
+	rejected  atomic.Int64 // 本次饱和期间拒绝的消息数
+}
+
+// newLanes 按优先级从高到低创建消息通道，未单独配置的通道使用 bufferSize
+func newLanes(bufferSize int, buffers map[string]int) []*lane {
+	lanes := make([]*lane, 0, len(parser.Priorities))
+	for _, priority := range parser.Priorities {
+		size := buffers[string(priority)]
+		if size <= 0 {
+			size = bufferSize
+		}
+		lanes = append(lanes, &lane{
+			priority: priority,
+			ch:       make(chan *queue.Entry, size),
+		})
+	}
+	return lanes
+}
+
+// laneFor 返回消息所属优先级的通道
+func (d *Dispatcher) laneFor(msg *parser.Message) *lane {
+	priority := msg.Priority
+	if priority == "" {
+		priority = parser.PriorityNormal
+	}
+	for _, l := range d.lanes {
+		if l.priority == priority {
+			return l
+		}
+	}
+	return d.lanes[len(d.lanes)/2]
+}
+
+// next 按优先级取出下一条消息，ctx 结束或所有通道关闭后返回 false
+//
+// 工作协程总是优先处理高优先级消息；skipped 按通道记录非空时被连续跳过的次数，
+// 达到 starvation 次的通道中优先级最高的一个强制取出一条消息，
+// 每个较低优先级的通道都能轮到，避免低优先级消息无限等待。
+func (d *Dispatcher) next(ctx context.Context, chans []chan *queue.Entry, skipped []int) (*queue.Entry, bool) {
+	for {
+		for i := range chans {
+			if skipped[i] < d.starvation {
+				continue
+			}
+			if entry, ok := tryReceive(chans, i); ok {
+				served(chans, skipped, i)
+				return entry, true
+			}
+			skipped[i] = 0
+		}
+
+		for i := range chans {
+			if entry, ok := tryReceive(chans, i); ok {
+				served(chans, skipped, i)
+				return entry, true
+			}
+		}
+
+		if allClosed(chans) {
+			return nil, false
+		}
+
+		// 所有通道均为空，阻塞等待任一通道；通道数随 parser.Priorities 变化，使用 reflect.Select
+		cases := make([]reflect.SelectCase, 0, len(chans)+1)
+		cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ctx.Done())})
+		for _, ch := range chans {
+			cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ch)})
+		}
+		chosen, value, ok := reflect.Select(cases)
+		if chosen == 0 {
+			return nil, false
+		}
+		if ok {
+			served(chans, skipped, chosen-1)
+			return value.Interface().(*queue.Entry), true
+		}
+		chans[chosen-1] = nil
+	}
+}
+
+// tryReceive 非阻塞地从第 i 个通道取出消息，通道已关闭时将其置为 nil
+func tryReceive(chans []chan *queue.Entry, i int) (*queue.Entry, bool) {
+	select {
+	case entry, ok := <-chans[i]:
+		if !ok {
+			chans[i] = nil
+			return nil, false
+		}
+		return entry, true
+	default:
+		return nil, false
+	}
+}
+
+// served 记录从第 i 个通道取出消息：重置该通道的计数，其后仍有积压的低优先级通道计数加一
+func served(chans []chan *queue.Entry, skipped []int, i int) {
+	skipped[i] = 0
+	for j := i + 1; j < len(chans); j++ {
+		if len(chans[j]) > 0 {
+			skipped[j]++
+		} else {
+			skipped[j] = 0
+		}
+	}
+}
+
+func allClosed(chans []chan *queue.Entry) bool {
+	for _, ch := range chans {
+		if ch != nil {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,91 @@
+package dispatcher
+
+import (
+	"math"
+	"math/rand"
+	"time"
+
+	"notify/internal/config"
+	"notify/internal/queue"
+	"notify/internal/sender"
+)
+
+// retryPolicy 带抖动的指数退避重试策略
+type retryPolicy struct {
+	maxAttempts    int
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+	multiplier     float64
+	jitter         float64
+	maxElapsed     time.Duration
+}
+
+func newRetryPolicy(cfg config.RetryConfig) retryPolicy {
+	p := retryPolicy{
+		maxAttempts:    cfg.MaxAttempts,
+		initialBackoff: cfg.InitialBackoff,
+		maxBackoff:     cfg.MaxBackoff,
+		multiplier:     cfg.Multiplier,
+		jitter:         cfg.Jitter,
+		maxElapsed:     cfg.MaxElapsed,
+	}
+	if p.maxAttempts <= 0 {
+		p.maxAttempts = 5
+	}
+	if p.initialBackoff <= 0 {
+		p.initialBackoff = time.Second
+	}
+	if p.maxBackoff <= 0 {
+		p.maxBackoff = 5 * time.Minute
+	}
+	if p.multiplier < 1 {
+		p.multiplier = 2
+	}
+	if p.jitter < 0 || p.jitter > 1 {
+		p.jitter = 0.2
+	}
+	return p
+}
+
+// backoff 计算第 attempt 次失败后的等待时间
+func (p retryPolicy) backoff(attempt int) time.Duration {
+	delay := float64(p.initialBackoff) * math.Pow(p.multiplier, float64(attempt-1))
+	if delay > float64(p.maxBackoff) {
+		delay = float64(p.maxBackoff)
+	}
+	if p.jitter > 0 {
+		// 在 [1-jitter, 1+jitter] 范围内随机浮动，避免大量消息同时重试
+		delay *= 1 + p.jitter*(2*rand.Float64()-1)
+	}
+	return time.Duration(delay)
+}
+
+// next 判断失败的消息是否继续重试，返回下次重试前的等待时间
+func (p retryPolicy) next(entry *queue.Entry, err error) (time.Duration, bool) {
+	if !sender.IsRetryable(err) {
+		return 0, false
+	}
+
+	attempt := len(entry.Attempts)
+	if attempt >= p.maxAttempts {
+		return 0, false
+	}
+
+	// 平台要求的重试间隔优先于退避时间，不受 maxBackoff 限制
+	delay := p.backoff(attempt)
+	if hint := sender.RetryDelay(err); hint > delay {
+		delay = hint
+	}
+	if p.maxElapsed > 0 && time.Now().Add(delay).After(retryStart(entry).Add(p.maxElapsed)) {
+		return 0, false
+	}
+	return delay, true
+}
+
+// retryStart 返回重试总时长的起点，定时消息从计划发送时间开始计算
+func retryStart(entry *queue.Entry) time.Time {
+	if sendAt := entry.Message.SendAt; sendAt != nil && sendAt.After(entry.EnqueuedAt) {
+		return *sendAt
+	}
+	return entry.EnqueuedAt
+}
@@ -0,0 +1,65 @@
+package dispatcher
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"notify/internal/queue"
+	"notify/pkg/logger"
+
+	"go.uber.org/zap"
+)
+
+// ErrNotScheduled 消息不是待发送的定时消息（已到期或不存在）
+var ErrNotScheduled = errors.New("message is not scheduled")
+
+// isScheduled 判断消息是否为尚未到期的定时消息
+func isScheduled(entry *queue.Entry, now time.Time) bool {
+	return len(entry.Attempts) == 0 && entry.Message.SendAt != nil && entry.Message.SendAt.After(now)
+}
+
+// Scheduled 返回所有尚未到期的定时消息，按入队时间排序
+func (d *Dispatcher) Scheduled() []*queue.Entry {
+	now := time.Now()
+	var entries []*queue.Entry
+	for _, entry := range d.store.List() {
+		if isScheduled(entry, now) {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// Cancel 取消尚未到期的定时消息
+func (d *Dispatcher) Cancel(id string) error {
+	entry, ok := d.store.Get(id)
+	if !ok {
+		return ErrNotFound
+	}
+	if !isScheduled(entry, time.Now()) {
+		return ErrNotScheduled
+	}
+
+	// 已从延迟队列取出的消息即将发送，不能再取消
+	if !d.delayed.remove(id) {
+		return ErrNotScheduled
+	}
+	if err := d.store.Delete(id); err != nil {
+		// 删除失败时放回延迟队列，保持与持久化队列一致
+		d.delayed.push(entry, *entry.Message.SendAt)
+		return fmt.Errorf("delete message failed: %w", err)
+	}
+	d.finish(entry, StateCancelled)
+
+	logger.Info("Scheduled message cancelled",
+		zap.String("id", id),
+		zap.Time("send_at", *entry.Message.SendAt))
+	return nil
+}
+
+// schedule 将定时消息放入延迟队列，到期后进入正常的分发流程
+func (d *Dispatcher) schedule(entry *queue.Entry) {
+	d.status.update(entry, StateScheduled)
+	d.delayed.push(entry, *entry.Message.SendAt)
+}
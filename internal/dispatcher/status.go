@@ -0,0 +1,207 @@
+package dispatcher
+
+import (
+	"sync"
+	"time"
+
+	"notify/internal/parser"
+	"notify/internal/queue"
+	"notify/pkg/logger"
+
+	"go.uber.org/zap"
+)
+
+// State 消息的投递状态
+type State string
+
+const (
+	StateScheduled State = "scheduled" // 等待定时发送
+	StateCancelled State = "cancelled" // 定时消息已取消
+	StateQueued    State = "queued"    // 已入队，等待发送
+	StateSending   State = "sending"   // 正在发送
+	StateSent      State = "sent"      // 发送成功
+	StatePartial   State = "partial"   // 部分接收人未送达
+	StateRetrying  State = "retrying"  // 发送失败，等待重试
+	StateFailed    State = "failed"    // 最终发送失败，已进入死信队列
+	StateExpired   State = "expired"   // 超过有效期未发送，已丢弃
+)
+
+// sweepInterval 清理过期状态记录的最小间隔
+const sweepInterval = time.Minute
+
+// Status 消息的生命周期信息
+type Status struct {
+	ID        string          `json:"id"`
+	State     State           `json:"state"`
+	Platform  parser.Platform `json:"platform"`
+	Sender    string          `json:"sender,omitempty"`
+	Error     string          `json:"error,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+	UpdatedAt time.Time       `json:"updated_at"`
+	Attempts  []queue.Attempt `json:"attempts"`
+}
+
+// statusTracker 在内存中记录消息状态，终态记录超过保留时间后清理
+type statusTracker struct {
+	mu        sync.Mutex
+	statuses  map[string]*Status
+	retention time.Duration
+	lastSweep time.Time
+}
+
+func newStatusTracker(retention time.Duration) *statusTracker {
+	if retention <= 0 {
+		retention = 24 * time.Hour
+	}
+	return &statusTracker{
+		statuses:  make(map[string]*Status),
+		retention: retention,
+		lastSweep: time.Now(),
+	}
+}
+
+// update 根据队列记录更新消息状态
+func (t *statusTracker) update(entry *queue.Entry, state State) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	status, ok := t.statuses[entry.ID]
+	if !ok {
+		status = &Status{
+			ID:        entry.ID,
+			Platform:  entry.Message.Platform,
+			CreatedAt: entry.EnqueuedAt,
+		}
+		t.statuses[entry.ID] = status
+	}
+	status.State = state
+	status.UpdatedAt = now
+	status.Attempts = append([]queue.Attempt(nil), entry.Attempts...)
+	if n := len(entry.Attempts); n > 0 {
+		status.Sender = entry.Attempts[n-1].Sender
+		status.Error = entry.Attempts[n-1].Error
+	}
+
+	if now.Sub(t.lastSweep) >= sweepInterval {
+		t.sweep(now)
+	}
+}
+
+// forget 删除消息状态
+func (t *statusTracker) forget(id string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.statuses, id)
+}
+
+// get 返回消息状态的副本
+func (t *statusTracker) get(id string) (*Status, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	status, ok := t.statuses[id]
+	if !ok {
+		return nil, false
+	}
+	clone := *status
+	return &clone, true
+}
+
+// sweep 清理超过保留时间的终态记录，需持有锁调用
+func (t *statusTracker) sweep(now time.Time) {
+	for id, status := range t.statuses {
+		terminal := status.State == StateSent || status.State == StatePartial || status.State == StateFailed ||
+			status.State == StateCancelled || status.State == StateExpired
+		if terminal && now.Sub(status.UpdatedAt) > t.retention {
+			delete(t.statuses, id)
+		}
+	}
+	t.lastSweep = now
+}
+
+// finish 记录消息的最终状态，并写入历史存储，进程重启后仍可查询
+func (d *Dispatcher) finish(entry *queue.Entry, state State) {
+	d.status.update(entry, state)
+
+	now := time.Now()
+	record := entry.Clone()
+	record.State = string(state)
+	record.FinishedAt = &now
+	if err := d.history.Put(record); err != nil {
+		logger.Error("Failed to persist message status",
+			zap.String("id", entry.ID),
+			zap.String("state", string(state)),
+			zap.Error(err))
+	}
+	d.sweepHistory(now)
+}
+
+// sweepHistory 删除超过状态保留时间且幂等键已过去重窗口的历史记录，每个 sweepInterval 最多执行一次
+func (d *Dispatcher) sweepHistory(now time.Time) {
+	last := d.swept.Load()
+	if last != 0 && now.Sub(time.Unix(0, last)) < sweepInterval {
+		return
+	}
+	if !d.swept.CompareAndSwap(last, now.UnixNano()) {
+		return
+	}
+	for _, entry := range d.history.List() {
+		if d.historyExpired(entry, now) && !d.idempotency.holds(entry, now) {
+			if err := d.history.Delete(entry.ID); err != nil {
+				logger.Warn("Failed to remove expired message status",
+					zap.String("id", entry.ID),
+					zap.Error(err))
+			}
+		}
+	}
+}
+
+// historyExpired 判断历史记录是否已超过状态保留时间
+func (d *Dispatcher) historyExpired(entry *queue.Entry, now time.Time) bool {
+	return entry.FinishedAt == nil || now.Sub(*entry.FinishedAt) > d.status.retention
+}
+
+// Status 查询消息状态，内存中没有记录时（如进程重启后）从持久化队列和历史记录中恢复
+func (d *Dispatcher) Status(id string) (*Status, bool) {
+	if status, ok := d.status.get(id); ok {
+		return status, true
+	}
+
+	if entry, ok := d.store.Get(id); ok {
+		state := StateQueued
+		if len(entry.Attempts) > 0 {
+			state = StateRetrying
+		} else if isScheduled(entry, time.Now()) {
+			state = StateScheduled
+		}
+		return statusFromEntry(entry, state), true
+	}
+	if entry, ok := d.deadLetters.Get(id); ok {
+		return statusFromEntry(entry, StateFailed), true
+	}
+	if entry, ok := d.history.Get(id); ok && !d.historyExpired(entry, time.Now()) {
+		status := statusFromEntry(entry, State(entry.State))
+		status.UpdatedAt = *entry.FinishedAt
+		return status, true
+	}
+	return nil, false
+}
+
+func statusFromEntry(entry *queue.Entry, state State) *Status {
+	status := &Status{
+		ID:        entry.ID,
+		State:     state,
+		Platform:  entry.Message.Platform,
+		CreatedAt: entry.EnqueuedAt,
+		UpdatedAt: entry.EnqueuedAt,
+		Attempts:  entry.Attempts,
+	}
+	if n := len(entry.Attempts); n > 0 {
+		last := entry.Attempts[n-1]
+		status.Sender = last.Sender
+		status.Error = last.Error
+		status.UpdatedAt = last.At
+	}
+	return status
+}
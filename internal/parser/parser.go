@@ -3,6 +3,7 @@ package parser
 import (
 	"encoding/json"
 	"errors"
+	"time"
 )
 
 type Platform string
@@ -10,13 +11,45 @@ type Platform string
 const (
 	PlatformWeChat   Platform = "wechat"
 	PlatformDingTalk Platform = "dingtalk"
+	PlatformFeishu   Platform = "feishu"
+	PlatformSlack    Platform = "slack"
+	PlatformTelegram Platform = "telegram"
+	PlatformDiscord  Platform = "discord"
+	PlatformEmail    Platform = "email"
 )
 
+// Priority 消息优先级
+type Priority string
+
+const (
+	PriorityHigh   Priority = "high"
+	PriorityNormal Priority = "normal"
+	PriorityLow    Priority = "low"
+)
+
+// Priorities 按从高到低排列的全部优先级
+var Priorities = []Priority{PriorityHigh, PriorityNormal, PriorityLow}
+
 type Message struct {
+	ID       string         `json:"id,omitempty"` // 由服务端在入队时生成
 	Platform Platform       `json:"platform"`
+	Channel  string         `json:"channel,omitempty"` // 命名通道，为空时使用平台的默认发送器
 	Content  string         `json:"content"`
 	Summary  string         `json:"summary,omitempty"`
 	Extra    map[string]any `json:"extra,omitempty"`
+	Priority Priority       `json:"priority,omitempty"` // 为空时为 normal
+
+	// IdempotencyKey 幂等键，去重窗口内相同键的请求只会发送一次
+	IdempotencyKey string `json:"idempotency_key,omitempty"`
+
+	// SendAt 定时发送时间（RFC3339），Delay 延迟发送时长（如 "10m"），二者只能指定一个
+	SendAt *time.Time `json:"send_at,omitempty"`
+	Delay  string     `json:"delay,omitempty"`
+
+	// ExpiresAt 过期时间（RFC3339），TTL 有效时长（如 "30m"，从计划发送时间起算），
+	// 二者只能指定一个，过期的消息不再发送
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	TTL       string     `json:"ttl,omitempty"`
 }
 
 func Parse(data []byte) (*Message, error) {
@@ -43,15 +76,96 @@ func (m *Message) Validate() error {
 	if !isValidPlatform(m.Platform) {
 		return errors.New("unsupported platform")
 	}
+	if m.Priority != "" && !isValidPriority(m.Priority) {
+		return errors.New("invalid priority")
+	}
+	if m.Delay != "" {
+		if m.SendAt != nil {
+			return errors.New("send_at and delay cannot be used together")
+		}
+		delay, err := time.ParseDuration(m.Delay)
+		if err != nil {
+			return errors.New("invalid delay")
+		}
+		if delay < 0 {
+			return errors.New("delay must not be negative")
+		}
+	}
+	if m.TTL != "" {
+		if m.ExpiresAt != nil {
+			return errors.New("expires_at and ttl cannot be used together")
+		}
+		ttl, err := time.ParseDuration(m.TTL)
+		if err != nil {
+			return errors.New("invalid ttl")
+		}
+		if ttl <= 0 {
+			return errors.New("ttl must be positive")
+		}
+	}
+	if m.ExpiresAt != nil && m.SendAt != nil && !m.ExpiresAt.After(*m.SendAt) {
+		return errors.New("expires_at must be later than send_at")
+	}
 	return nil
 }
 
+// ResolveSchedule 将 delay 换算为 send_at，返回计划发送时间，未指定时返回零值
+func (m *Message) ResolveSchedule(now time.Time) time.Time {
+	if m.Delay != "" {
+		if delay, err := time.ParseDuration(m.Delay); err == nil {
+			at := now.Add(delay)
+			m.SendAt = &at
+		}
+		m.Delay = ""
+	}
+	if m.SendAt == nil {
+		return time.Time{}
+	}
+	return *m.SendAt
+}
+
+// ResolveExpiry 将 ttl 换算为 expires_at，未指定时使用 defaultTTL，
+// 有效时长从计划发送时间（未定时则为 now）起算
+func (m *Message) ResolveExpiry(now time.Time, defaultTTL time.Duration) {
+	base := now
+	if m.SendAt != nil && m.SendAt.After(now) {
+		base = *m.SendAt
+	}
+
+	if m.TTL != "" {
+		if ttl, err := time.ParseDuration(m.TTL); err == nil {
+			at := base.Add(ttl)
+			m.ExpiresAt = &at
+		}
+		m.TTL = ""
+	}
+	if m.ExpiresAt == nil && defaultTTL > 0 {
+		at := base.Add(defaultTTL)
+		m.ExpiresAt = &at
+	}
+}
+
+// Expired 判断消息在 now 时刻是否已过期
+func (m *Message) Expired(now time.Time) bool {
+	return m.ExpiresAt != nil && !now.Before(*m.ExpiresAt)
+}
+
 // isValidPlatform 检查平台是否支持
 func isValidPlatform(platform Platform) bool {
 	switch platform {
-	case PlatformWeChat, PlatformDingTalk:
+	case PlatformWeChat, PlatformDingTalk, PlatformFeishu, PlatformSlack, PlatformTelegram, PlatformDiscord, PlatformEmail:
 		return true
 	default:
 		return false
 	}
 }
+
+// isValidPriority 检查优先级是否合法
+func isValidPriority(priority Priority) bool {
+	for _, p := range Priorities {
+		if p == priority {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,288 @@
+package queue
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"notify/pkg/logger"
+
+	"go.uber.org/zap"
+)
+
+const (
+	opPut    = "put"
+	opDelete = "del"
+
+	// compactThreshold 删除记录累计超过该值且多于存活记录时触发压缩
+	compactThreshold = 1024
+)
+
+// record 预写日志中的一行
+type record struct {
+	Op    string `json:"op"`
+	ID    string `json:"id,omitempty"`
+	Entry *Entry `json:"entry,omitempty"`
+}
+
+// FileStore 基于预写日志（WAL）的文件存储
+//
+// 每次写入都会追加一行 JSON 记录并 fsync，启动时通过回放日志恢复未删除的记录。
+// 删除记录累积过多时会重写日志文件以回收空间。
+type FileStore struct {
+	mu      sync.Mutex
+	path    string
+	file    *os.File
+	entries map[string]*Entry
+	garbage int  // 自上次压缩以来已失效的日志行数
+	broken  bool // 写入失败后未能截断残留的部分记录，下次写入前需重写日志
+}
+
+// NewFileStore 打开或创建日志文件，并回放其中的记录
+func NewFileStore(path string) (*FileStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("create queue dir failed: %w", err)
+	}
+
+	s := &FileStore{
+		path:    path,
+		entries: make(map[string]*Entry),
+	}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+
+	// 启动时压缩一次，丢弃已删除的记录和损坏的尾部
+	if err := s.compact(); err != nil {
+		return nil, err
+	}
+
+	logger.Info("Queue store opened",
+		zap.String("path", path),
+		zap.Int("pending", len(s.entries)))
+
+	return s, nil
+}
+
+func (s *FileStore) Put(entry *Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.write(record{Op: opPut, Entry: entry}); err != nil {
+		return err
+	}
+	if _, ok := s.entries[entry.ID]; ok {
+		s.garbage++
+	}
+	s.entries[entry.ID] = entry.Clone()
+	return nil
+}
+
+func (s *FileStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.entries[id]; !ok {
+		return nil
+	}
+	if err := s.write(record{Op: opDelete, ID: id}); err != nil {
+		return err
+	}
+	delete(s.entries, id)
+	s.garbage += 2
+
+	if s.garbage >= compactThreshold && s.garbage > len(s.entries) {
+		if err := s.compact(); err != nil {
+			// 压缩失败不影响正确性，下次删除时重试
+			logger.Warn("Queue compaction failed",
+				zap.String("path", s.path),
+				zap.Error(err))
+		}
+	}
+	return nil
+}
+
+func (s *FileStore) Get(id string) (*Entry, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, ok := s.entries[id]
+	if !ok {
+		return nil, false
+	}
+	return entry.Clone(), true
+}
+
+func (s *FileStore) List() []*Entry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return sortedEntries(s.entries)
+}
+
+func (s *FileStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
+
+// write 追加一条记录并落盘，失败时撤销已写入的部分
+func (s *FileStore) write(rec record) error {
+	if s.file == nil {
+		return fmt.Errorf("queue store closed")
+	}
+	if s.broken {
+		if err := s.compact(); err != nil {
+			return fmt.Errorf("repair queue file failed: %w", err)
+		}
+		s.broken = false
+	}
+
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("marshal record failed: %w", err)
+	}
+	data = append(data, '\n')
+
+	offset, err := s.file.Seek(0, io.SeekEnd)
+	if err != nil {
+		s.broken = true
+		return fmt.Errorf("seek queue file failed: %w", err)
+	}
+	if _, err := s.file.Write(data); err != nil {
+		s.rollback(offset)
+		return fmt.Errorf("write record failed: %w", err)
+	}
+	if err := s.file.Sync(); err != nil {
+		s.rollback(offset)
+		return fmt.Errorf("sync record failed: %w", err)
+	}
+	return nil
+}
+
+// rollback 截断写入失败的记录，避免残留的半行与下一条记录拼接后一起无法解析；
+// 截断失败时标记日志损坏，下次写入前通过压缩重写日志
+func (s *FileStore) rollback(offset int64) {
+	if err := s.file.Truncate(offset); err != nil {
+		logger.Warn("Failed to truncate queue file after write error",
+			zap.String("path", s.path),
+			zap.Error(err))
+		s.broken = true
+	}
+}
+
+// load 回放日志文件，重建内存中的记录
+func (s *FileStore) load() error {
+	f, err := os.Open(s.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("open queue file failed: %w", err)
+	}
+	defer f.Close()
+
+	reader := bufio.NewReader(f)
+	line := 0
+	for {
+		data, err := reader.ReadBytes('\n')
+		if len(data) > 0 {
+			line++
+			var rec record
+			if jsonErr := json.Unmarshal(data, &rec); jsonErr != nil {
+				// 进程崩溃时最后一行可能只写入了一半，跳过即可
+				logger.Warn("Skip corrupted queue record",
+					zap.String("path", s.path),
+					zap.Int("line", line),
+					zap.Error(jsonErr))
+			} else {
+				s.apply(rec)
+			}
+		}
+		if err != nil {
+			break
+		}
+	}
+	return nil
+}
+
+func (s *FileStore) apply(rec record) {
+	switch rec.Op {
+	case opPut:
+		if rec.Entry != nil && rec.Entry.ID != "" {
+			s.entries[rec.Entry.ID] = rec.Entry
+		}
+	case opDelete:
+		delete(s.entries, rec.ID)
+	}
+}
+
+// compact 将存活记录写入临时文件后原子替换原日志
+func (s *FileStore) compact() error {
+	tmpPath := s.path + ".tmp"
+	tmp, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("create compact file failed: %w", err)
+	}
+
+	writer := bufio.NewWriter(tmp)
+	for _, entry := range sortedEntries(s.entries) {
+		data, err := json.Marshal(record{Op: opPut, Entry: entry})
+		if err != nil {
+			tmp.Close()
+			return fmt.Errorf("marshal record failed: %w", err)
+		}
+		writer.Write(data)
+		writer.WriteByte('\n')
+	}
+	if err := writer.Flush(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write compact file failed: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("sync compact file failed: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("close compact file failed: %w", err)
+	}
+
+	if s.file != nil {
+		s.file.Close()
+		s.file = nil
+	}
+	renameErr := os.Rename(tmpPath, s.path)
+	if renameErr == nil {
+		syncDir(filepath.Dir(s.path))
+	}
+
+	// 无论替换是否成功都重新打开日志，保证后续写入可用
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("open queue file failed: %w", err)
+	}
+	s.file = f
+	if renameErr != nil {
+		return fmt.Errorf("replace queue file failed: %w", renameErr)
+	}
+	s.garbage = 0
+	return nil
+}
+
+// syncDir 刷新目录项，保证重命名在断电后仍然可见
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	d.Sync()
+	d.Close()
+}
@@ -0,0 +1,132 @@
+package queue
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"notify/internal/parser"
+)
+
+// Entry 队列中的一条消息记录
+type Entry struct {
+	ID         string          `json:"id"`
+	Message    *parser.Message `json:"message"`
+	EnqueuedAt time.Time       `json:"enqueued_at"`
+	Attempts   []Attempt       `json:"attempts,omitempty"`
+	// State 和 FinishedAt 仅在保存已完成消息的存储中使用，记录最终状态和得出结果的时间
+	State      string     `json:"state,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+// Clone 复制记录，存储内部只保存副本，避免调用方修改时产生数据竞争
+func (e *Entry) Clone() *Entry {
+	clone := *e
+	clone.Attempts = append([]Attempt(nil), e.Attempts...)
+	return &clone
+}
+
+// Attempt 一次发送尝试的记录
+type Attempt struct {
+	At     time.Time `json:"at"`
+	Sender string    `json:"sender,omitempty"`
+	Error  string    `json:"error,omitempty"`
+	// Receipts 平台返回的消息ID，键为接收人
+	Receipts map[string]string `json:"receipts,omitempty"`
+}
+
+// Store 消息存储接口，写入成功即代表消息已被记录
+type Store interface {
+	// Put 写入或覆盖一条记录
+	Put(entry *Entry) error
+	// Delete 删除一条记录，记录不存在时不返回错误
+	Delete(id string) error
+	// Get 获取一条记录
+	Get(id string) (*Entry, bool)
+	// List 按入队时间顺序返回所有记录
+	List() []*Entry
+	Close() error
+}
+
+// Open 打开名为 name 的存储，dir 为空时使用内存存储
+func Open(dir, name string) (Store, error) {
+	if dir == "" {
+		return NewMemoryStore(), nil
+	}
+	return NewFileStore(filepath.Join(dir, name+".wal"))
+}
+
+// NewID 生成消息ID，前 12 位为毫秒时间戳，保证大致按时间排序
+func NewID() string {
+	var buf [16]byte
+	var ts [8]byte
+	binary.BigEndian.PutUint64(ts[:], uint64(time.Now().UnixMilli()))
+	copy(buf[:6], ts[2:])
+	_, _ = rand.Read(buf[6:])
+	return hex.EncodeToString(buf[:])
+}
+
+// MemoryStore 内存存储，进程退出后数据丢失
+type MemoryStore struct {
+	mu      sync.Mutex
+	entries map[string]*Entry
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		entries: make(map[string]*Entry),
+	}
+}
+
+func (s *MemoryStore) Put(entry *Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[entry.ID] = entry.Clone()
+	return nil
+}
+
+func (s *MemoryStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, id)
+	return nil
+}
+
+func (s *MemoryStore) Get(id string) (*Entry, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, ok := s.entries[id]
+	if !ok {
+		return nil, false
+	}
+	return entry.Clone(), true
+}
+
+func (s *MemoryStore) List() []*Entry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return sortedEntries(s.entries)
+}
+
+func (s *MemoryStore) Close() error {
+	return nil
+}
+
+// sortedEntries 按入队时间排序，时间相同时按ID排序
+func sortedEntries(entries map[string]*Entry) []*Entry {
+	list := make([]*Entry, 0, len(entries))
+	for _, entry := range entries {
+		list = append(list, entry.Clone())
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if !list[i].EnqueuedAt.Equal(list[j].EnqueuedAt) {
+			return list[i].EnqueuedAt.Before(list[j].EnqueuedAt)
+		}
+		return list[i].ID < list[j].ID
+	})
+	return list
+}
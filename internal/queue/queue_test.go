@@ -0,0 +1,187 @@
+package queue
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"notify/internal/parser"
+)
+
+func newEntry(content string) *Entry {
+	return &Entry{
+		ID:         NewID(),
+		Message:    &parser.Message{Platform: parser.PlatformWeChat, Content: content},
+		EnqueuedAt: time.Now(),
+	}
+}
+
+func TestFileStoreReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pending.wal")
+
+	store, err := NewFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, second, third := newEntry("first"), newEntry("second"), newEntry("third")
+	for _, entry := range []*Entry{first, second, third} {
+		if err := store.Put(entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := store.Delete(second.ID); err != nil {
+		t.Fatal(err)
+	}
+	store.Close()
+
+	// 重新打开后应只剩下未删除的记录，且顺序不变
+	store, err = NewFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	pending := store.List()
+	if len(pending) != 2 {
+		t.Fatalf("Expected 2 pending entries, got %d", len(pending))
+	}
+	if pending[0].ID != first.ID || pending[1].ID != third.ID {
+		t.Errorf("Unexpected replay order: %s, %s", pending[0].ID, pending[1].ID)
+	}
+	if pending[0].Message.Content != "first" {
+		t.Errorf("Expected content 'first', got '%s'", pending[0].Message.Content)
+	}
+}
+
+func TestFileStoreTornWrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pending.wal")
+
+	store, err := NewFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry := newEntry("complete")
+	if err := store.Put(entry); err != nil {
+		t.Fatal(err)
+	}
+	store.Close()
+
+	// 模拟崩溃时写入了一半的记录
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"op":"put","entry":{"id":"broken`)
+	f.Close()
+
+	store, err = NewFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	if _, ok := store.Get(entry.ID); !ok {
+		t.Error("Expected complete entry to survive torn write")
+	}
+	if n := len(store.List()); n != 1 {
+		t.Errorf("Expected 1 pending entry, got %d", n)
+	}
+}
+
+func TestFileStoreWriteError(t *testing.T) {
+	const torn = `{"op":"put","entry":{"id":"torn`
+
+	tests := []struct {
+		name string
+		fail func(t *testing.T, store *FileStore)
+	}{
+		{
+			// 写入中途失败，截断残留的部分记录
+			name: "truncate",
+			fail: func(t *testing.T, store *FileStore) {
+				offset, err := store.file.Seek(0, io.SeekEnd)
+				if err != nil {
+					t.Fatal(err)
+				}
+				store.file.WriteString(torn)
+				store.rollback(offset)
+			},
+		},
+		{
+			// 写入失败且无法截断，下次写入前重写日志
+			name: "repair",
+			fail: func(t *testing.T, store *FileStore) {
+				store.file.WriteString(torn)
+				store.file.Close()
+				if err := store.Put(newEntry("failed")); err == nil {
+					t.Fatal("Expected put to fail on closed file")
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "pending.wal")
+			store, err := NewFileStore(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			first, next := newEntry("first"), newEntry("next")
+			if err := store.Put(first); err != nil {
+				t.Fatal(err)
+			}
+			tt.fail(t, store)
+
+			// 失败后写入成功的记录在重启后不能丢失
+			if err := store.Put(next); err != nil {
+				t.Fatal(err)
+			}
+			store.Close()
+
+			store, err = NewFileStore(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer store.Close()
+			for _, entry := range []*Entry{first, next} {
+				if _, ok := store.Get(entry.ID); !ok {
+					t.Errorf("Expected entry '%s' to survive write error", entry.Message.Content)
+				}
+			}
+			if n := len(store.List()); n != 2 {
+				t.Errorf("Expected 2 pending entries, got %d", n)
+			}
+		})
+	}
+}
+
+func TestFileStoreCompaction(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pending.wal")
+
+	store, err := NewFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	for i := 0; i < compactThreshold; i++ {
+		entry := newEntry("message")
+		if err := store.Put(entry); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.Delete(entry.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size() > 4096 {
+		t.Errorf("Expected queue file to be compacted, size is %d", info.Size())
+	}
+}
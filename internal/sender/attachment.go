@@ -0,0 +1,98 @@
+package sender
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// Attachment 消息附件，内容来自 extra 中的 base64 数据或下载地址
+type Attachment struct {
+	Name string
+	URL  string
+	Data []byte
+}
+
+// ParseAttachment 从 extra 中读取附件：media_base64 或 media_url 二选一，filename 为可选文件名
+//
+// 未指定附件时返回 nil。base64 内容在这里解码，格式错误的请求可以在接收时被拒绝。
+func ParseAttachment(extra map[string]any) (*Attachment, error) {
+	encoded, err := ExtraString(extra, "media_base64")
+	if err != nil {
+		return nil, err
+	}
+	url, err := ExtraString(extra, "media_url")
+	if err != nil {
+		return nil, err
+	}
+	name, err := ExtraString(extra, "filename")
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case encoded == "" && url == "":
+		return nil, nil
+	case encoded != "" && url != "":
+		return nil, fmt.Errorf("extra.media_base64 and extra.media_url are mutually exclusive")
+	}
+
+	attachment := &Attachment{Name: name, URL: url}
+	if encoded != "" {
+		// 兼容 data:image/png;base64,xxx 形式
+		if i := strings.Index(encoded, ","); i >= 0 && strings.HasPrefix(encoded, "data:") {
+			encoded = encoded[i+1:]
+		}
+		attachment.Data, err = base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("extra.media_base64 is not valid base64: %w", err)
+		}
+	} else if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+		return nil, fmt.Errorf("extra.media_url must be an http or https url")
+	}
+
+	if attachment.Name == "" {
+		attachment.Name = "attachment"
+		if url != "" {
+			if base := path.Base(strings.SplitN(url, "?", 2)[0]); base != "" && base != "/" && base != "." {
+				attachment.Name = base
+			}
+		}
+	}
+	return attachment, nil
+}
+
+// Fetch 下载 media_url 指向的附件内容，内容已存在时直接返回；超过 maxSize 字节的附件视为不可重试错误
+func (a *Attachment) Fetch(ctx context.Context, client *http.Client, maxSize int64) error {
+	if a.Data == nil {
+		req, err := http.NewRequestWithContext(ctx, "GET", a.URL, nil)
+		if err != nil {
+			return Permanent(fmt.Errorf("create download request failed: %w", err))
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return Retryable(fmt.Errorf("download attachment failed: %w", err))
+		}
+		defer resp.Body.Close()
+
+		if err := CheckStatus(resp); err != nil {
+			return err
+		}
+
+		a.Data, err = io.ReadAll(io.LimitReader(resp.Body, maxSize+1))
+		if err != nil {
+			a.Data = nil
+			return Retryable(fmt.Errorf("download attachment failed: %w", err))
+		}
+	}
+
+	if int64(len(a.Data)) > maxSize {
+		return Permanent(fmt.Errorf("attachment %s exceeds %d bytes", a.Name, maxSize))
+	}
+	return nil
+}
@@ -17,41 +17,77 @@ import (
 	"go.uber.org/zap"
 )
 
-type DingTalkSender struct {
-	config config.DingTalkConfig
-	client *http.Client
+// dingTalkRetryableCodes 钉钉返回的可重试错误码（系统繁忙、发送过快被限流）
+var dingTalkRetryableCodes = map[int]bool{
+	-1:     true,
+	130101: true,
+	410100: true,
 }
 
-type DingTalkMessage struct {
-	MsgType string `json:"msgtype"`
-	Text    struct {
-		Content string `json:"content"`
-	} `json:"text"`
+// dingTalkAPIBase 钉钉开放平台官方地址
+const dingTalkAPIBase = "https://oapi.dingtalk.com"
+
+// dingTalkContentLimit 文本和 Markdown 正文的最大字节数
+const dingTalkContentLimit = 20000
+
+type DingTalkSender struct {
+	config  config.DingTalkConfig
+	client  *http.Client
+	baseURL string
 }
 
-func NewDingTalkSender(config config.DingTalkConfig) *DingTalkSender {
+func NewDingTalkSender(config config.DingTalkConfig) (*DingTalkSender, error) {
+	if err := CheckOversize(config.Oversize); err != nil {
+		return nil, err
+	}
+	client, err := NewHTTPClient(config.HTTP)
+	if err != nil {
+		return nil, fmt.Errorf("create dingtalk http client failed: %w", err)
+	}
 	return &DingTalkSender{
-		config: config,
-		client: &http.Client{
-			Timeout: 10 * time.Second,
-		},
+		config:  config,
+		client:  client,
+		baseURL: BaseURL(config.HTTP, dingTalkAPIBase),
+	}, nil
+}
+
+func (s *DingTalkSender) Name() string {
+	return "dingtalk"
+}
+
+// ContentLimit 文本和 Markdown 消息的正文长度限制，其他类型不拆分
+//
+// 正文末尾会补充 @提醒文本，需为其预留空间。
+func (s *DingTalkSender) ContentLimit(extra map[string]any) (int, string) {
+	switch msgType, _ := ExtraString(extra, "msgtype"); msgType {
+	case "", DingTalkMsgText, DingTalkMsgMarkdown:
+		at, _ := parseDingTalkAt(extra)
+		return dingTalkContentLimit - len(appendDingTalkMentions("", at)), s.config.Oversize
+	default:
+		return 0, s.config.Oversize
 	}
 }
 
+// SummaryInTitle Markdown 消息的摘要作为标题发送，文本消息的摘要写在正文前
+func (s *DingTalkSender) SummaryInTitle(extra map[string]any) bool {
+	msgType, _ := ExtraString(extra, "msgtype")
+	return msgType == DingTalkMsgMarkdown
+}
+
+// Validate 校验 extra 中指定的消息类型及其必填字段
+func (s *DingTalkSender) Validate(content string, summary string, extra map[string]any) error {
+	_, err := buildDingTalkMessage(content, summary, extra)
+	return err
+}
+
 func (s *DingTalkSender) Send(ctx context.Context, content string, summary string, extra map[string]any) error {
-	// 如果有摘要，添加到消息内容前面
-	messageContent := content
-	if summary != "" {
-		messageContent = fmt.Sprintf("【%s】\n\n%s", summary, content)
+	// 拆分后的消息只在第一部分 @提醒，避免重复提醒
+	if PartIndex(ctx) > 0 {
+		extra = withoutDingTalkMentions(extra)
 	}
-
-	msg := DingTalkMessage{
-		MsgType: "text",
-		Text: struct {
-			Content string `json:"content"`
-		}{
-			Content: messageContent,
-		},
+	msg, err := buildDingTalkMessage(content, summary, extra)
+	if err != nil {
+		return Permanent(err)
 	}
 
 	// 生成签名
@@ -60,7 +96,8 @@ func (s *DingTalkSender) Send(ctx context.Context, content string, summary strin
 
 	// 构建URL
 	url := fmt.Sprintf(
-		"https://oapi.dingtalk.com/robot/send?access_token=%s&timestamp=%d&sign=%s",
+		"%s/robot/send?access_token=%s&timestamp=%d&sign=%s",
+		s.baseURL,
 		s.config.AccessToken,
 		timestamp,
 		sign,
@@ -69,34 +106,43 @@ func (s *DingTalkSender) Send(ctx context.Context, content string, summary strin
 	// 发送消息
 	body, err := json.Marshal(msg)
 	if err != nil {
-		return fmt.Errorf("marshal message failed: %w", err)
+		return Permanent(fmt.Errorf("marshal message failed: %w", err))
 	}
 
 	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
 	if err != nil {
-		return fmt.Errorf("create request failed: %w", err)
+		return Permanent(fmt.Errorf("create request failed: %w", err))
 	}
 	req.Header.Set("Content-Type", "application/json")
 
 	resp, err := s.client.Do(req)
 	if err != nil {
-		return fmt.Errorf("send request failed: %w", err)
+		return Retryable(fmt.Errorf("send request failed: %w", err))
 	}
 	defer resp.Body.Close()
 
+	if err := CheckStatus(resp); err != nil {
+		return err
+	}
+
 	var result struct {
 		ErrCode int    `json:"errcode"`
 		ErrMsg  string `json:"errmsg"`
 	}
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return fmt.Errorf("decode response failed: %w", err)
+		return Retryable(fmt.Errorf("decode response failed: %w", err))
 	}
 
 	if result.ErrCode != 0 {
-		return fmt.Errorf("send message failed: %s", result.ErrMsg)
+		err := fmt.Errorf("send message failed: [%d] %s", result.ErrCode, result.ErrMsg)
+		if dingTalkRetryableCodes[result.ErrCode] {
+			return Retryable(err)
+		}
+		return Permanent(err)
 	}
 
 	logger.Info("DingTalk message sent successfully",
+		zap.String("msgtype", msg.MsgType),
 		zap.String("content", content))
 
 	return nil
@@ -0,0 +1,282 @@
+package sender
+
+import (
+	"fmt"
+	"strings"
+)
+
+// 钉钉机器人支持的消息类型
+const (
+	DingTalkMsgText       = "text"
+	DingTalkMsgMarkdown   = "markdown"
+	DingTalkMsgLink       = "link"
+	DingTalkMsgActionCard = "actionCard"
+	DingTalkMsgFeedCard   = "feedCard"
+)
+
+type DingTalkMessage struct {
+	MsgType    string              `json:"msgtype"`
+	Text       *dingTalkText       `json:"text,omitempty"`
+	Markdown   *dingTalkMarkdown   `json:"markdown,omitempty"`
+	Link       *dingTalkLink       `json:"link,omitempty"`
+	ActionCard *dingTalkActionCard `json:"actionCard,omitempty"`
+	FeedCard   *dingTalkFeedCard   `json:"feedCard,omitempty"`
+	At         *dingTalkAt         `json:"at,omitempty"`
+}
+
+// dingTalkAt @提醒，仅文本和 Markdown 消息支持
+type dingTalkAt struct {
+	AtMobiles []string `json:"atMobiles,omitempty"`
+	AtUserIds []string `json:"atUserIds,omitempty"`
+	IsAtAll   bool     `json:"isAtAll,omitempty"`
+}
+
+type dingTalkText struct {
+	Content string `json:"content"`
+}
+
+type dingTalkMarkdown struct {
+	Title string `json:"title"`
+	Text  string `json:"text"`
+}
+
+type dingTalkLink struct {
+	Title      string `json:"title"`
+	Text       string `json:"text"`
+	MessageURL string `json:"messageUrl"`
+	PicURL     string `json:"picUrl,omitempty"`
+}
+
+type dingTalkActionCard struct {
+	Title          string              `json:"title"`
+	Text           string              `json:"text"`
+	SingleTitle    string              `json:"singleTitle,omitempty"`
+	SingleURL      string              `json:"singleURL,omitempty"`
+	BtnOrientation string              `json:"btnOrientation,omitempty"`
+	Btns           []dingTalkActionBtn `json:"btns,omitempty"`
+}
+
+type dingTalkActionBtn struct {
+	Title     string `json:"title"`
+	ActionURL string `json:"actionURL"`
+}
+
+type dingTalkFeedCard struct {
+	Links []dingTalkFeedLink `json:"links"`
+}
+
+type dingTalkFeedLink struct {
+	Title      string `json:"title"`
+	MessageURL string `json:"messageURL"`
+	PicURL     string `json:"picURL,omitempty"`
+}
+
+// buildDingTalkMessage 根据 extra["msgtype"] 构建钉钉消息，缺省为文本消息
+//
+// 各类型使用的 extra 字段：
+//   - markdown: title（缺省使用摘要）
+//   - link: title、message_url、pic_url
+//   - actionCard: title、single_title + single_url 或 buttons[{title, action_url}]、btn_orientation
+//   - feedCard: links[{title, message_url, pic_url}]
+//
+// 文本和 Markdown 消息还可通过 at_mobiles、at_user_ids、at_all 提醒群成员
+func buildDingTalkMessage(content string, summary string, extra map[string]any) (*DingTalkMessage, error) {
+	msgType, err := ExtraString(extra, "msgtype")
+	if err != nil {
+		return nil, err
+	}
+	at, err := parseDingTalkAt(extra)
+	if err != nil {
+		return nil, err
+	}
+	if at != nil && msgType != "" && msgType != DingTalkMsgText && msgType != DingTalkMsgMarkdown {
+		return nil, fmt.Errorf("dingtalk %s message does not support mentions", msgType)
+	}
+	title, err := ExtraString(extra, "title")
+	if err != nil {
+		return nil, err
+	}
+	if title == "" {
+		title = summary
+	}
+
+	switch msgType {
+	case "", DingTalkMsgText:
+		// 如果有摘要，添加到消息内容前面
+		text := content
+		if summary != "" {
+			text = fmt.Sprintf("【%s】\n\n%s", summary, content)
+		}
+		return &DingTalkMessage{
+			MsgType: DingTalkMsgText,
+			Text:    &dingTalkText{Content: appendDingTalkMentions(text, at)},
+			At:      at,
+		}, nil
+
+	case DingTalkMsgMarkdown:
+		if title == "" {
+			return nil, fmt.Errorf("markdown message requires a title or summary")
+		}
+		return &DingTalkMessage{
+			MsgType:  DingTalkMsgMarkdown,
+			Markdown: &dingTalkMarkdown{Title: title, Text: appendDingTalkMentions(content, at)},
+			At:       at,
+		}, nil
+
+	case DingTalkMsgLink:
+		link := &dingTalkLink{Title: title, Text: content}
+		if link.MessageURL, err = ExtraString(extra, "message_url"); err != nil {
+			return nil, err
+		}
+		if link.PicURL, err = ExtraString(extra, "pic_url"); err != nil {
+			return nil, err
+		}
+		if link.Title == "" || link.MessageURL == "" {
+			return nil, fmt.Errorf("link message requires a title and extra.message_url")
+		}
+		return &DingTalkMessage{MsgType: DingTalkMsgLink, Link: link}, nil
+
+	case DingTalkMsgActionCard:
+		card, err := buildDingTalkActionCard(title, content, extra)
+		if err != nil {
+			return nil, err
+		}
+		return &DingTalkMessage{MsgType: DingTalkMsgActionCard, ActionCard: card}, nil
+
+	case DingTalkMsgFeedCard:
+		links, err := ExtraObjects(extra, "links")
+		if err != nil {
+			return nil, err
+		}
+		if len(links) == 0 {
+			return nil, fmt.Errorf("feedCard message requires extra.links")
+		}
+		card := &dingTalkFeedCard{}
+		for i, item := range links {
+			var link dingTalkFeedLink
+			if link.Title, err = ExtraString(item, "title"); err != nil {
+				return nil, err
+			}
+			if link.MessageURL, err = ExtraString(item, "message_url"); err != nil {
+				return nil, err
+			}
+			if link.PicURL, err = ExtraString(item, "pic_url"); err != nil {
+				return nil, err
+			}
+			if link.Title == "" || link.MessageURL == "" {
+				return nil, fmt.Errorf("extra.links[%d] requires title and message_url", i)
+			}
+			card.Links = append(card.Links, link)
+		}
+		return &DingTalkMessage{MsgType: DingTalkMsgFeedCard, FeedCard: card}, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported dingtalk msgtype: %s", msgType)
+	}
+}
+
+// buildDingTalkActionCard 构建卡片消息，单按钮与多按钮二选一
+func buildDingTalkActionCard(title string, content string, extra map[string]any) (*dingTalkActionCard, error) {
+	if title == "" {
+		return nil, fmt.Errorf("actionCard message requires a title or summary")
+	}
+
+	card := &dingTalkActionCard{Title: title, Text: content}
+	var err error
+	if card.SingleTitle, err = ExtraString(extra, "single_title"); err != nil {
+		return nil, err
+	}
+	if card.SingleURL, err = ExtraString(extra, "single_url"); err != nil {
+		return nil, err
+	}
+	if card.BtnOrientation, err = ExtraString(extra, "btn_orientation"); err != nil {
+		return nil, err
+	}
+	if card.BtnOrientation != "" && card.BtnOrientation != "0" && card.BtnOrientation != "1" {
+		return nil, fmt.Errorf("extra.btn_orientation must be \"0\" or \"1\"")
+	}
+
+	buttons, err := ExtraObjects(extra, "buttons")
+	if err != nil {
+		return nil, err
+	}
+	for i, item := range buttons {
+		var btn dingTalkActionBtn
+		if btn.Title, err = ExtraString(item, "title"); err != nil {
+			return nil, err
+		}
+		if btn.ActionURL, err = ExtraString(item, "action_url"); err != nil {
+			return nil, err
+		}
+		if btn.Title == "" || btn.ActionURL == "" {
+			return nil, fmt.Errorf("extra.buttons[%d] requires title and action_url", i)
+		}
+		card.Btns = append(card.Btns, btn)
+	}
+
+	single := card.SingleTitle != "" || card.SingleURL != ""
+	switch {
+	case single && len(card.Btns) > 0:
+		return nil, fmt.Errorf("actionCard message accepts either single_title/single_url or buttons, not both")
+	case single && (card.SingleTitle == "" || card.SingleURL == ""):
+		return nil, fmt.Errorf("actionCard message requires both extra.single_title and extra.single_url")
+	case !single && len(card.Btns) == 0:
+		return nil, fmt.Errorf("actionCard message requires extra.single_title/single_url or extra.buttons")
+	}
+	return card, nil
+}
+
+// parseDingTalkAt 读取 @提醒设置，未指定任何提醒对象时返回 nil
+func parseDingTalkAt(extra map[string]any) (*dingTalkAt, error) {
+	var at dingTalkAt
+	var err error
+	if at.AtMobiles, err = ExtraStrings(extra, "at_mobiles"); err != nil {
+		return nil, err
+	}
+	if at.AtUserIds, err = ExtraStrings(extra, "at_user_ids"); err != nil {
+		return nil, err
+	}
+	if at.IsAtAll, err = ExtraBool(extra, "at_all"); err != nil {
+		return nil, err
+	}
+	if len(at.AtMobiles) == 0 && len(at.AtUserIds) == 0 && !at.IsAtAll {
+		return nil, nil
+	}
+	return &at, nil
+}
+
+// appendDingTalkMentions 在正文末尾补充 @手机号 和 @用户ID，钉钉只有正文中包含这些文本时才会高亮提醒
+func appendDingTalkMentions(content string, at *dingTalkAt) string {
+	if at == nil {
+		return content
+	}
+
+	var mentions []string
+	for _, mobile := range at.AtMobiles {
+		if !strings.Contains(content, "@"+mobile) {
+			mentions = append(mentions, "@"+mobile)
+		}
+	}
+	for _, userID := range at.AtUserIds {
+		if !strings.Contains(content, "@"+userID) {
+			mentions = append(mentions, "@"+userID)
+		}
+	}
+	if len(mentions) == 0 {
+		return content
+	}
+	return content + "\n\n" + strings.Join(mentions, " ")
+}
+
+// withoutDingTalkMentions 返回去掉 @提醒设置的 extra 副本
+func withoutDingTalkMentions(extra map[string]any) map[string]any {
+	copied := make(map[string]any, len(extra))
+	for k, v := range extra {
+		switch k {
+		case "at_mobiles", "at_user_ids", "at_all":
+		default:
+			copied[k] = v
+		}
+	}
+	return copied
+}
@@ -0,0 +1,209 @@
+package sender
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"notify/internal/config"
+	"notify/internal/parser"
+)
+
+func TestBuildDingTalkMessage(t *testing.T) {
+	tests := []struct {
+		name     string
+		summary  string
+		extra    map[string]any
+		wantType string
+		wantErr  bool
+	}{
+		{
+			name:     "default text",
+			wantType: DingTalkMsgText,
+		},
+		{
+			name:     "markdown uses summary as title",
+			summary:  "告警",
+			extra:    map[string]any{"msgtype": "markdown"},
+			wantType: DingTalkMsgMarkdown,
+		},
+		{
+			name:    "markdown without title",
+			extra:   map[string]any{"msgtype": "markdown"},
+			wantErr: true,
+		},
+		{
+			name:     "link",
+			extra:    map[string]any{"msgtype": "link", "title": "runbook", "message_url": "https://example.com"},
+			wantType: DingTalkMsgLink,
+		},
+		{
+			name:    "link without url",
+			extra:   map[string]any{"msgtype": "link", "title": "runbook"},
+			wantErr: true,
+		},
+		{
+			name:     "single button actionCard",
+			extra:    map[string]any{"msgtype": "actionCard", "title": "发布", "single_title": "查看", "single_url": "https://example.com"},
+			wantType: DingTalkMsgActionCard,
+		},
+		{
+			name: "multi button actionCard",
+			extra: map[string]any{"msgtype": "actionCard", "title": "发布", "buttons": []any{
+				map[string]any{"title": "同意", "action_url": "https://example.com/yes"},
+				map[string]any{"title": "拒绝", "action_url": "https://example.com/no"},
+			}},
+			wantType: DingTalkMsgActionCard,
+		},
+		{
+			name: "actionCard with both button styles",
+			extra: map[string]any{"msgtype": "actionCard", "title": "发布", "single_title": "查看", "single_url": "https://example.com",
+				"buttons": []any{map[string]any{"title": "同意", "action_url": "https://example.com/yes"}}},
+			wantErr: true,
+		},
+		{
+			name: "feedCard",
+			extra: map[string]any{"msgtype": "feedCard", "links": []any{
+				map[string]any{"title": "日报", "message_url": "https://example.com/1", "pic_url": "https://example.com/1.png"},
+			}},
+			wantType: DingTalkMsgFeedCard,
+		},
+		{
+			name:    "feedCard without links",
+			extra:   map[string]any{"msgtype": "feedCard"},
+			wantErr: true,
+		},
+		{
+			name:    "unknown msgtype",
+			extra:   map[string]any{"msgtype": "voice"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg, err := buildDingTalkMessage("content", tt.summary, tt.extra)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if msg.MsgType != tt.wantType {
+				t.Errorf("Expected msgtype %s, got %s", tt.wantType, msg.MsgType)
+			}
+		})
+	}
+}
+
+func TestBuildDingTalkMessageMentions(t *testing.T) {
+	extra := map[string]any{
+		"msgtype":     "markdown",
+		"title":       "告警",
+		"at_mobiles":  []any{"13800000000"},
+		"at_user_ids": []any{"manager01"},
+	}
+	msg, err := buildDingTalkMessage("磁盘已满", "", extra)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if msg.At == nil || len(msg.At.AtMobiles) != 1 || len(msg.At.AtUserIds) != 1 {
+		t.Fatalf("Expected at block with mobile and user id, got %+v", msg.At)
+	}
+	if want := "磁盘已满\n\n@13800000000 @manager01"; msg.Markdown.Text != want {
+		t.Errorf("Expected text %q, got %q", want, msg.Markdown.Text)
+	}
+
+	// 正文中已经包含 @手机号 时不重复追加
+	msg, err = buildDingTalkMessage("@13800000000 请处理", "", map[string]any{"at_mobiles": "13800000000"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if want := "@13800000000 请处理"; msg.Text.Content != want {
+		t.Errorf("Expected text %q, got %q", want, msg.Text.Content)
+	}
+
+	// 链接消息不支持 @提醒
+	if _, err := buildDingTalkMessage("x", "", map[string]any{"msgtype": "link", "title": "t", "message_url": "https://example.com", "at_all": true}); err == nil {
+		t.Errorf("Expected error for mentions on link message, got nil")
+	}
+}
+
+func TestDingTalkSendBaseURL(t *testing.T) {
+	var sent DingTalkMessage
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/robot/send" || r.URL.Query().Get("access_token") != "token" || r.URL.Query().Get("sign") == "" {
+			t.Errorf("Unexpected request %s", r.URL)
+		}
+		json.NewDecoder(r.Body).Decode(&sent)
+		w.Write([]byte(`{"errcode":0,"errmsg":"ok"}`))
+	}))
+	defer server.Close()
+
+	s, err := NewDingTalkSender(config.DingTalkConfig{
+		AccessToken: "token",
+		Secret:      "secret",
+		HTTP:        config.HTTPConfig{BaseURL: server.URL + "/"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Send(context.Background(), "hello", "", nil); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if sent.Text == nil || sent.Text.Content != "hello" {
+		t.Errorf("Expected text content hello, got %+v", sent.Text)
+	}
+}
+
+func TestDingTalkSendOversizeMentions(t *testing.T) {
+	var sent []DingTalkMessage
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var msg DingTalkMessage
+		json.NewDecoder(r.Body).Decode(&msg)
+		sent = append(sent, msg)
+		w.Write([]byte(`{"errcode":0,"errmsg":"ok"}`))
+	}))
+	defer server.Close()
+
+	s, err := NewDingTalkSender(config.DingTalkConfig{AccessToken: "token", HTTP: config.HTTPConfig{BaseURL: server.URL}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mgr := NewManager()
+	mgr.Register(parser.PlatformDingTalk, s)
+
+	var mobiles []any
+	for i := 0; i < 50; i++ {
+		mobiles = append(mobiles, "138000000"+strconv.Itoa(10+i))
+	}
+	err = mgr.Send(context.Background(), &parser.Message{
+		Platform: parser.PlatformDingTalk,
+		Content:  strings.Repeat("- disk usage above threshold\n", 1500),
+		Extra:    map[string]any{"at_mobiles": mobiles},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(sent) < 2 {
+		t.Fatalf("Expected content to be split, got %d messages", len(sent))
+	}
+
+	// 补充 @提醒后各部分仍不超过限制，且只有第一部分提醒
+	for i, msg := range sent {
+		if n := len(msg.Text.Content); n > dingTalkContentLimit {
+			t.Errorf("Part %d is %d bytes, exceeds %d", i+1, n, dingTalkContentLimit)
+		}
+		mentioned := msg.At != nil && strings.Contains(msg.Text.Content, "@13800000010")
+		if mentioned != (i == 0) {
+			t.Errorf("Expected mentions only on the first part, part %d mentioned=%t", i+1, mentioned)
+		}
+	}
+}
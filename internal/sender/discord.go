@@ -0,0 +1,252 @@
+package sender
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"notify/internal/config"
+	"notify/pkg/logger"
+
+	"go.uber.org/zap"
+)
+
+const (
+	discordTitleLimit       = 256  // embed 标题的最大长度
+	discordDescriptionLimit = 4096 // embed 描述的最大长度
+	// discordMaxInlineWait 被限流时在发送过程中等待的最长时间，超过后交给分发器延迟重试
+	discordMaxInlineWait = 5 * time.Second
+)
+
+// discordSeverityColors embed 左侧色条颜色，按 extra["severity"] 选择
+var discordSeverityColors = map[string]int{
+	"":         0x3498DB,
+	"info":     0x3498DB,
+	"success":  0x2ECC71,
+	"warning":  0xE67E22,
+	"error":    0xE74C3C,
+	"critical": 0x992D22,
+}
+
+type DiscordSender struct {
+	config config.DiscordConfig
+	client *http.Client
+
+	// resetAt 之前 webhook 的限流额度已用完，发送前需等待
+	mu      sync.Mutex
+	resetAt time.Time
+}
+
+type discordMessage struct {
+	Username  string         `json:"username,omitempty"`
+	AvatarURL string         `json:"avatar_url,omitempty"`
+	Embeds    []discordEmbed `json:"embeds"`
+}
+
+type discordEmbed struct {
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	Color       int    `json:"color"`
+}
+
+// discordRateLimit 429 响应体，retry_after 单位为秒
+type discordRateLimit struct {
+	Message    string  `json:"message"`
+	RetryAfter float64 `json:"retry_after"`
+	Global     bool    `json:"global"`
+}
+
+func NewDiscordSender(config config.DiscordConfig) (*DiscordSender, error) {
+	if err := CheckOversize(config.Oversize); err != nil {
+		return nil, err
+	}
+	client, err := NewHTTPClient(config.HTTP)
+	if err != nil {
+		return nil, fmt.Errorf("create discord http client failed: %w", err)
+	}
+	return &DiscordSender{
+		config: config,
+		client: client,
+	}, nil
+}
+
+func (s *DiscordSender) Name() string {
+	return "discord"
+}
+
+// ContentLimit 正文长度限制，正文作为 embed 描述发送
+func (s *DiscordSender) ContentLimit(extra map[string]any) (int, string) {
+	return discordDescriptionLimit, s.config.Oversize
+}
+
+// SummaryInTitle 摘要作为 embed 标题发送
+func (s *DiscordSender) SummaryInTitle(extra map[string]any) bool {
+	return true
+}
+
+// Validate 校验 severity 和显示名称等字段
+func (s *DiscordSender) Validate(content string, summary string, extra map[string]any) error {
+	_, err := s.buildMessage(content, summary, extra)
+	return err
+}
+
+// Send 通过 webhook 发送，被限流时短暂等待后重发一次，等待时间较长时返回带重试间隔的错误
+func (s *DiscordSender) Send(ctx context.Context, content string, summary string, extra map[string]any) error {
+	msg, err := s.buildMessage(content, summary, extra)
+	if err != nil {
+		return Permanent(err)
+	}
+	if s.config.WebhookURL == "" {
+		return Permanent(fmt.Errorf("discord webhook_url is required"))
+	}
+
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return Permanent(fmt.Errorf("marshal message failed: %w", err))
+	}
+
+	for attempt := 0; ; attempt++ {
+		if err := s.wait(ctx); err != nil {
+			return err
+		}
+		err := s.post(ctx, body)
+		if attempt == 0 {
+			if delay := RetryDelay(err); delay > 0 && delay <= discordMaxInlineWait {
+				continue
+			}
+		}
+		return err
+	}
+}
+
+// post 发送一次 webhook 请求，并根据响应头记录限流状态
+func (s *DiscordSender) post(ctx context.Context, body []byte) error {
+	// wait=true 时 Discord 返回创建的消息，可以拿到消息ID
+	target, err := url.Parse(s.config.WebhookURL)
+	if err != nil {
+		return Permanent(fmt.Errorf("parse webhook url failed: %w", err))
+	}
+	query := target.Query()
+	query.Set("wait", "true")
+	target.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, "POST", target.String(), bytes.NewReader(body))
+	if err != nil {
+		return Permanent(fmt.Errorf("create request failed: %w", err))
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return Retryable(fmt.Errorf("send request failed: %w", err))
+	}
+	defer resp.Body.Close()
+
+	if resp.Header.Get("X-RateLimit-Remaining") == "0" {
+		s.delay(parseRetryAfter(resp.Header.Get("X-RateLimit-Reset-After")))
+	}
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		var limit discordRateLimit
+		json.NewDecoder(resp.Body).Decode(&limit)
+		delay := time.Duration(limit.RetryAfter * float64(time.Second))
+		if delay <= 0 {
+			delay = parseRetryAfter(resp.Header.Get("Retry-After"))
+		}
+		s.delay(delay)
+		return RetryAfter(fmt.Errorf("rate limited by discord (global=%t), retry after %s", limit.Global, delay), delay)
+	}
+
+	if err := CheckStatus(resp); err != nil {
+		// 错误原因（如 Invalid Webhook Token）在响应体中
+		reason, _ := io.ReadAll(io.LimitReader(resp.Body, 256))
+		return fmt.Errorf("%w: %s", err, bytes.TrimSpace(reason))
+	}
+
+	var result struct {
+		ID        string `json:"id"`
+		ChannelID string `json:"channel_id"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err == nil && result.ID != "" {
+		RecordReceipt(ctx, "channel:"+result.ChannelID, result.ID)
+	}
+	logger.Info("Discord message sent successfully",
+		zap.String("message_id", result.ID))
+
+	return nil
+}
+
+// delay 记录限流解除时间，之后的发送需等待到该时间
+func (s *DiscordSender) delay(d time.Duration) {
+	if d <= 0 {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if resetAt := time.Now().Add(d); resetAt.After(s.resetAt) {
+		s.resetAt = resetAt
+	}
+}
+
+// wait 等待限流解除，等待时间超过 discordMaxInlineWait 时直接返回带重试间隔的错误
+func (s *DiscordSender) wait(ctx context.Context) error {
+	s.mu.Lock()
+	d := time.Until(s.resetAt)
+	s.mu.Unlock()
+
+	if d <= 0 {
+		return nil
+	}
+	if d > discordMaxInlineWait {
+		return RetryAfter(fmt.Errorf("discord webhook is rate limited, retry after %s", d), d)
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// buildMessage 将摘要映射为 embed 标题，正文映射为 embed 描述
+//
+// 支持的 extra 字段：severity（info、success、warning、error、critical，决定色条颜色）、
+// username 和 avatar_url（覆盖配置的显示名称和头像）。
+func (s *DiscordSender) buildMessage(content string, summary string, extra map[string]any) (*discordMessage, error) {
+	severity, err := ExtraString(extra, "severity")
+	if err != nil {
+		return nil, err
+	}
+	color, ok := discordSeverityColors[severity]
+	if !ok {
+		return nil, fmt.Errorf("extra.severity must be one of info, success, warning, error, critical")
+	}
+
+	msg := &discordMessage{Username: s.config.Username, AvatarURL: s.config.AvatarURL}
+	if username, err := ExtraString(extra, "username"); err != nil {
+		return nil, err
+	} else if username != "" {
+		msg.Username = username
+	}
+	if avatarURL, err := ExtraString(extra, "avatar_url"); err != nil {
+		return nil, err
+	} else if avatarURL != "" {
+		msg.AvatarURL = avatarURL
+	}
+
+	msg.Embeds = []discordEmbed{{
+		Title:       summary[:runeBoundary(summary, discordTitleLimit)],
+		Description: content,
+		Color:       color,
+	}}
+	return msg, nil
+}
@@ -0,0 +1,154 @@
+package sender
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"notify/internal/config"
+)
+
+func TestDiscordSend(t *testing.T) {
+	tests := []struct {
+		name       string
+		extra      map[string]any
+		wantColor  int
+		wantName   string
+		wantAvatar string
+		wantErr    bool
+	}{
+		{
+			name:       "default severity with configured identity",
+			extra:      map[string]any{},
+			wantColor:  0x3498DB,
+			wantName:   "notify",
+			wantAvatar: "https://example.com/bot.png",
+		},
+		{
+			name:       "critical with identity override",
+			extra:      map[string]any{"severity": "critical", "username": "oncall", "avatar_url": "https://example.com/pager.png"},
+			wantColor:  0x992D22,
+			wantName:   "oncall",
+			wantAvatar: "https://example.com/pager.png",
+		},
+		{
+			name:    "unknown severity",
+			extra:   map[string]any{"severity": "fatal"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sent discordMessage
+			var query string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				query = r.URL.RawQuery
+				json.NewDecoder(r.Body).Decode(&sent)
+				w.Write([]byte(`{"id":"1001","channel_id":"42"}`))
+			}))
+			defer server.Close()
+
+			s, err := NewDiscordSender(config.DiscordConfig{
+				WebhookURL: server.URL + "/api/webhooks/1/token?thread_id=9",
+				Username:   "notify",
+				AvatarURL:  "https://example.com/bot.png",
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ctx, receipt := WithReceipt(context.Background())
+			err = s.Send(ctx, "disk usage 91%", "Disk alert", tt.extra)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if query != "thread_id=9&wait=true" {
+				t.Errorf("Expected wait=true to be added to query, got %s", query)
+			}
+			if len(sent.Embeds) != 1 || sent.Embeds[0].Title != "Disk alert" || sent.Embeds[0].Description != "disk usage 91%" {
+				t.Fatalf("Expected summary as title and content as description, got %+v", sent.Embeds)
+			}
+			if sent.Embeds[0].Color != tt.wantColor {
+				t.Errorf("Expected color %#x, got %#x", tt.wantColor, sent.Embeds[0].Color)
+			}
+			if sent.Username != tt.wantName || sent.AvatarURL != tt.wantAvatar {
+				t.Errorf("Expected identity %s %s, got %s %s", tt.wantName, tt.wantAvatar, sent.Username, sent.AvatarURL)
+			}
+			if ids := receipt.IDs(); ids["channel:42"] != "1001" {
+				t.Errorf("Expected receipt for channel 42, got %v", ids)
+			}
+		})
+	}
+}
+
+func TestDiscordRateLimit(t *testing.T) {
+	tests := []struct {
+		name       string
+		retryAfter string
+		wantCalls  int32
+		wantDelay  time.Duration
+	}{
+		{
+			// 等待时间较短时在发送过程中等待后重发
+			name:       "short retry_after waits inline",
+			retryAfter: "0.05",
+			wantCalls:  2,
+		},
+		{
+			// 等待时间较长时交给分发器按 retry_after 延迟重试
+			name:       "long retry_after returned to dispatcher",
+			retryAfter: "30",
+			wantCalls:  1,
+			wantDelay:  30 * time.Second,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if calls.Add(1) == 1 {
+					w.WriteHeader(http.StatusTooManyRequests)
+					w.Write([]byte(`{"message":"You are being rate limited.","retry_after":` + tt.retryAfter + `,"global":false}`))
+					return
+				}
+				w.Write([]byte(`{"id":"1001","channel_id":"42"}`))
+			}))
+			defer server.Close()
+
+			s, err := NewDiscordSender(config.DiscordConfig{WebhookURL: server.URL})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = s.Send(context.Background(), "content", "", nil)
+			if calls.Load() != tt.wantCalls {
+				t.Errorf("Expected %d requests, got %d", tt.wantCalls, calls.Load())
+			}
+			if tt.wantDelay == 0 {
+				if err != nil {
+					t.Errorf("Expected no error, got %v", err)
+				}
+				return
+			}
+			if !IsRetryable(err) || RetryDelay(err) != tt.wantDelay {
+				t.Errorf("Expected retryable error with delay %s, got %v (delay %s)", tt.wantDelay, err, RetryDelay(err))
+			}
+			// 限流解除前的后续发送不再请求 Discord
+			if err := s.Send(context.Background(), "content", "", nil); RetryDelay(err) <= 0 || calls.Load() != tt.wantCalls {
+				t.Errorf("Expected send to be held back until rate limit resets, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,228 @@
+package sender
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"time"
+
+	"notify/internal/config"
+	"notify/pkg/logger"
+
+	"go.uber.org/zap"
+)
+
+// SMTP 连接的加密方式
+const (
+	EmailSecurityNone     = "none"     // 明文连接
+	EmailSecuritySTARTTLS = "starttls" // 明文连接后通过 STARTTLS 升级
+	EmailSecurityTLS      = "tls"      // 隐式 TLS（SMTPS）
+)
+
+const (
+	defaultEmailTimeout = 30 * time.Second
+	// emailAttachmentMaxSize 单个附件的最大字节数
+	emailAttachmentMaxSize = 20 * 1024 * 1024
+)
+
+type EmailSender struct {
+	config    config.EmailConfig
+	client    *http.Client
+	tlsConfig *tls.Config
+}
+
+func NewEmailSender(config config.EmailConfig) (*EmailSender, error) {
+	if config.Security == "" {
+		config.Security = EmailSecuritySTARTTLS
+		if config.Port == 465 {
+			config.Security = EmailSecurityTLS
+		}
+	}
+	if config.Port == 0 {
+		switch config.Security {
+		case EmailSecurityNone:
+			config.Port = 25
+		case EmailSecurityTLS:
+			config.Port = 465
+		default:
+			config.Port = 587
+		}
+	}
+	switch config.Security {
+	case EmailSecurityNone, EmailSecuritySTARTTLS, EmailSecurityTLS:
+	default:
+		return nil, fmt.Errorf("invalid email security: %s", config.Security)
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = defaultEmailTimeout
+	}
+
+	tlsConfig := &tls.Config{ServerName: config.Host}
+	if config.CAFile != "" {
+		pool, err := loadCertPool(config.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	client, err := NewHTTPClient(config.HTTP)
+	if err != nil {
+		return nil, fmt.Errorf("create email http client failed: %w", err)
+	}
+	return &EmailSender{
+		config:    config,
+		client:    client,
+		tlsConfig: tlsConfig,
+	}, nil
+}
+
+func (s *EmailSender) Name() string {
+	return "email"
+}
+
+// Validate 校验发件人、收件人地址和附件
+func (s *EmailSender) Validate(content string, summary string, extra map[string]any) error {
+	_, err := s.buildMessage(content, summary, extra)
+	return err
+}
+
+// Send 通过 SMTP 发送邮件，部分收件人被服务器拒绝时返回部分送达错误
+func (s *EmailSender) Send(ctx context.Context, content string, summary string, extra map[string]any) error {
+	msg, err := s.buildMessage(content, summary, extra)
+	if err != nil {
+		return Permanent(err)
+	}
+	if s.config.Host == "" {
+		return Permanent(fmt.Errorf("email smtp host is required"))
+	}
+
+	for _, attachment := range msg.attachments {
+		if err := attachment.Fetch(ctx, s.client, emailAttachmentMaxSize); err != nil {
+			return err
+		}
+	}
+
+	messageID := newMessageID(msg.from)
+	data, err := msg.render(messageID, time.Now())
+	if err != nil {
+		return Permanent(fmt.Errorf("render email failed: %w", err))
+	}
+	return s.deliver(ctx, msg, messageID, data)
+}
+
+// deliver 完成一次 SMTP 会话
+func (s *EmailSender) deliver(ctx context.Context, msg *emailMessage, messageID string, data []byte) error {
+	conn, err := s.dial(ctx)
+	if err != nil {
+		return smtpError("connect", err)
+	}
+	deadline := time.Now().Add(s.config.Timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	conn.SetDeadline(deadline)
+
+	client, err := smtp.NewClient(conn, s.config.Host)
+	if err != nil {
+		conn.Close()
+		return smtpError("greeting", err)
+	}
+	defer client.Close()
+
+	if s.config.Security == EmailSecuritySTARTTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return Permanent(fmt.Errorf("smtp server does not support STARTTLS"))
+		}
+		if err := client.StartTLS(s.tlsConfig); err != nil {
+			return smtpError("starttls", err)
+		}
+	}
+	if s.config.Username != "" {
+		auth := smtp.PlainAuth("", s.config.Username, s.config.Password, s.config.Host)
+		if err := client.Auth(auth); err != nil {
+			return smtpError("auth", err)
+		}
+	}
+
+	if err := client.Mail(msg.from.Address); err != nil {
+		return smtpError("mail", err)
+	}
+	var accepted, failed []string
+	var rejectErr error
+	for _, rcpt := range msg.recipients() {
+		err := client.Rcpt(rcpt)
+		if err == nil {
+			accepted = append(accepted, rcpt)
+			continue
+		}
+		// 5xx 表示该收件人被拒绝，其余收件人仍可发送
+		var protoErr *textproto.Error
+		if !errors.As(err, &protoErr) || protoErr.Code < 500 {
+			return smtpError("rcpt", err)
+		}
+		logger.Warn("Email recipient rejected",
+			zap.String("recipient", rcpt),
+			zap.Error(err))
+		failed = append(failed, "email:"+rcpt)
+		rejectErr = err
+	}
+	if len(accepted) == 0 {
+		return smtpError("rcpt", rejectErr)
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return smtpError("data", err)
+	}
+	if _, err := w.Write(data); err != nil {
+		return smtpError("data", err)
+	}
+	if err := w.Close(); err != nil {
+		return smtpError("data", err)
+	}
+	client.Quit()
+
+	for _, rcpt := range accepted {
+		RecordReceipt(ctx, "email:"+rcpt, messageID)
+	}
+	logger.Info("Email sent successfully",
+		zap.String("message_id", messageID),
+		zap.Int("recipients", len(accepted)))
+
+	if len(failed) > 0 {
+		return Partial(failed)
+	}
+	return nil
+}
+
+func (s *EmailSender) dial(ctx context.Context) (net.Conn, error) {
+	addr := net.JoinHostPort(s.config.Host, strconv.Itoa(s.config.Port))
+	dialer := &net.Dialer{Timeout: s.config.Timeout}
+	if s.config.Security == EmailSecurityTLS {
+		tlsDialer := &tls.Dialer{NetDialer: dialer, Config: s.tlsConfig}
+		return tlsDialer.DialContext(ctx, "tcp", addr)
+	}
+	return dialer.DialContext(ctx, "tcp", addr)
+}
+
+// smtpError 按 SMTP 响应码分类：5xx 不可重试，4xx 和网络错误可重试
+func smtpError(action string, err error) error {
+	err = fmt.Errorf("smtp %s failed: %w", action, err)
+	var protoErr *textproto.Error
+	if errors.As(err, &protoErr) && protoErr.Code >= 500 {
+		return Permanent(err)
+	}
+	// 证书校验失败属于配置错误，重试无法恢复
+	var certErr *tls.CertificateVerificationError
+	if errors.As(err, &certErr) {
+		return Permanent(err)
+	}
+	return Retryable(err)
+}
@@ -0,0 +1,306 @@
+package sender
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"html/template"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/http"
+	"net/mail"
+	"net/textproto"
+	"path"
+	"strings"
+	"time"
+)
+
+// emailSubjectLimit 未指定主题时，从正文首行截取的最大字节数
+const emailSubjectLimit = 78
+
+// emailHTMLTemplate HTML 正文模板，正文按空行分段，段内换行转为 <br>
+var emailHTMLTemplate = template.Must(template.New("email").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"></head>
+<body style="font-family: sans-serif; line-height: 1.5;">
+{{- if .Summary}}
+<h2>{{.Summary}}</h2>
+{{- end}}
+{{- range .Paragraphs}}
+<p>{{range $i, $line := .}}{{if $i}}<br>{{end}}{{$line}}{{end}}</p>
+{{- end}}
+</body>
+</html>
+`))
+
+type emailMessage struct {
+	from        *mail.Address
+	to          []*mail.Address
+	cc          []*mail.Address
+	bcc         []*mail.Address
+	subject     string
+	summary     string
+	content     string
+	attachments []*Attachment
+}
+
+// buildMessage 解析收件人、主题和附件
+//
+// 支持的 extra 字段：
+//   - to、cc、bcc：收件人地址，字符串或列表，均未指定时发送给配置的默认收件人
+//   - subject：邮件主题，缺省使用摘要，摘要为空时使用正文首行
+//   - attachments：附件列表 [{filename, media_url | media_base64}]；单个附件也可直接使用 media_url 或 media_base64
+func (s *EmailSender) buildMessage(content string, summary string, extra map[string]any) (*emailMessage, error) {
+	from := s.config.From
+	if from == "" {
+		from = s.config.Username
+	}
+	if from == "" {
+		return nil, fmt.Errorf("email from address is not configured")
+	}
+
+	msg := &emailMessage{summary: summary, content: content}
+	var err error
+	if msg.from, err = mail.ParseAddress(from); err != nil {
+		return nil, fmt.Errorf("invalid email from address: %w", err)
+	}
+
+	if msg.to, err = parseEmailAddresses(extra, "to"); err != nil {
+		return nil, err
+	}
+	if msg.cc, err = parseEmailAddresses(extra, "cc"); err != nil {
+		return nil, err
+	}
+	if msg.bcc, err = parseEmailAddresses(extra, "bcc"); err != nil {
+		return nil, err
+	}
+	if len(msg.to)+len(msg.cc)+len(msg.bcc) == 0 {
+		if msg.to, err = parseEmailAddresses(map[string]any{"to": s.config.To}, "to"); err != nil {
+			return nil, err
+		}
+	}
+	if len(msg.to)+len(msg.cc)+len(msg.bcc) == 0 {
+		return nil, fmt.Errorf("email message requires extra.to, extra.cc or extra.bcc when no default recipients are configured")
+	}
+
+	if msg.subject, err = ExtraString(extra, "subject"); err != nil {
+		return nil, err
+	}
+	if msg.subject == "" {
+		msg.subject = summary
+	}
+	if msg.subject == "" {
+		line, _, _ := strings.Cut(strings.TrimSpace(content), "\n")
+		msg.subject = line[:runeBoundary(line, emailSubjectLimit)]
+	}
+	// 主题中的换行会破坏邮件头
+	msg.subject = strings.Join(strings.Fields(msg.subject), " ")
+
+	attachment, err := ParseAttachment(extra)
+	if err != nil {
+		return nil, err
+	}
+	if attachment != nil {
+		msg.attachments = append(msg.attachments, attachment)
+	}
+	items, err := ExtraObjects(extra, "attachments")
+	if err != nil {
+		return nil, err
+	}
+	for i, item := range items {
+		attachment, err := ParseAttachment(item)
+		if err != nil {
+			return nil, fmt.Errorf("extra.attachments[%d]: %w", i, err)
+		}
+		if attachment == nil {
+			return nil, fmt.Errorf("extra.attachments[%d] requires media_url or media_base64", i)
+		}
+		msg.attachments = append(msg.attachments, attachment)
+	}
+	return msg, nil
+}
+
+// parseEmailAddresses 读取并校验 extra 中的邮件地址列表
+func parseEmailAddresses(extra map[string]any, key string) ([]*mail.Address, error) {
+	values, err := ExtraStrings(extra, key)
+	if err != nil {
+		return nil, err
+	}
+	addresses := make([]*mail.Address, 0, len(values))
+	for _, value := range values {
+		address, err := mail.ParseAddress(value)
+		if err != nil {
+			return nil, fmt.Errorf("extra.%s contains invalid address %q: %w", key, value, err)
+		}
+		addresses = append(addresses, address)
+	}
+	return addresses, nil
+}
+
+// recipients 返回 SMTP 会话中的全部收件人，包括密送
+func (m *emailMessage) recipients() []string {
+	var list []string
+	for _, group := range [][]*mail.Address{m.to, m.cc, m.bcc} {
+		for _, address := range group {
+			list = append(list, address.Address)
+		}
+	}
+	return list
+}
+
+// render 生成 MIME 邮件：纯文本和 HTML 正文组成 multipart/alternative，有附件时外层为 multipart/mixed
+//
+// 密送地址不写入邮件头。
+func (m *emailMessage) render(messageID string, now time.Time) ([]byte, error) {
+	htmlBody, err := renderEmailHTML(m.summary, m.content)
+	if err != nil {
+		return nil, err
+	}
+
+	var body bytes.Buffer
+	alternative := multipart.NewWriter(&body)
+	for _, part := range []struct{ contentType, text string }{
+		{"text/plain; charset=utf-8", m.content},
+		{"text/html; charset=utf-8", htmlBody},
+	} {
+		if err := writeQuotedPrintable(alternative, part.contentType, part.text); err != nil {
+			return nil, err
+		}
+	}
+	if err := alternative.Close(); err != nil {
+		return nil, err
+	}
+	contentType := "multipart/alternative; boundary=" + alternative.Boundary()
+
+	if len(m.attachments) > 0 {
+		var mixedBody bytes.Buffer
+		mixed := multipart.NewWriter(&mixedBody)
+		part, err := mixed.CreatePart(textproto.MIMEHeader{"Content-Type": {contentType}})
+		if err != nil {
+			return nil, err
+		}
+		if _, err := part.Write(body.Bytes()); err != nil {
+			return nil, err
+		}
+		for _, attachment := range m.attachments {
+			if err := writeEmailAttachment(mixed, attachment); err != nil {
+				return nil, err
+			}
+		}
+		if err := mixed.Close(); err != nil {
+			return nil, err
+		}
+		body = mixedBody
+		contentType = "multipart/mixed; boundary=" + mixed.Boundary()
+	}
+
+	var buf bytes.Buffer
+	writeHeader := func(name, value string) {
+		fmt.Fprintf(&buf, "%s: %s\r\n", name, value)
+	}
+	writeHeader("From", m.from.String())
+	if len(m.to) > 0 {
+		writeHeader("To", joinEmailAddresses(m.to))
+	}
+	if len(m.cc) > 0 {
+		writeHeader("Cc", joinEmailAddresses(m.cc))
+	}
+	writeHeader("Subject", mime.QEncoding.Encode("utf-8", m.subject))
+	writeHeader("Date", now.Format(time.RFC1123Z))
+	writeHeader("Message-ID", messageID)
+	writeHeader("MIME-Version", "1.0")
+	writeHeader("Content-Type", contentType)
+	buf.WriteString("\r\n")
+	buf.Write(body.Bytes())
+	return buf.Bytes(), nil
+}
+
+// renderEmailHTML 将纯文本正文转义后渲染为 HTML
+func renderEmailHTML(summary string, content string) (string, error) {
+	var paragraphs [][]string
+	for _, block := range strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n\n") {
+		if block = strings.Trim(block, "\n"); block != "" {
+			paragraphs = append(paragraphs, strings.Split(block, "\n"))
+		}
+	}
+
+	var buf bytes.Buffer
+	err := emailHTMLTemplate.Execute(&buf, struct {
+		Summary    string
+		Paragraphs [][]string
+	}{summary, paragraphs})
+	if err != nil {
+		return "", fmt.Errorf("render html body failed: %w", err)
+	}
+	return buf.String(), nil
+}
+
+func writeQuotedPrintable(w *multipart.Writer, contentType string, text string) error {
+	part, err := w.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {contentType},
+		"Content-Transfer-Encoding": {"quoted-printable"},
+	})
+	if err != nil {
+		return err
+	}
+	qp := quotedprintable.NewWriter(part)
+	if _, err := io.WriteString(qp, text); err != nil {
+		return err
+	}
+	return qp.Close()
+}
+
+func writeEmailAttachment(w *multipart.Writer, attachment *Attachment) error {
+	contentType := mime.TypeByExtension(path.Ext(attachment.Name))
+	if contentType == "" {
+		contentType = http.DetectContentType(attachment.Data)
+	}
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType, params = "application/octet-stream", map[string]string{}
+	}
+	params["name"] = attachment.Name
+
+	part, err := w.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {mime.FormatMediaType(mediaType, params)},
+		"Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Name})},
+		"Content-Transfer-Encoding": {"base64"},
+	})
+	if err != nil {
+		return err
+	}
+
+	// base64 内容按每行 76 个字符换行
+	encoded := base64.StdEncoding.EncodeToString(attachment.Data)
+	for len(encoded) > 76 {
+		if _, err := io.WriteString(part, encoded[:76]+"\r\n"); err != nil {
+			return err
+		}
+		encoded = encoded[76:]
+	}
+	_, err = io.WriteString(part, encoded+"\r\n")
+	return err
+}
+
+func joinEmailAddresses(addresses []*mail.Address) string {
+	list := make([]string, len(addresses))
+	for i, address := range addresses {
+		list[i] = address.String()
+	}
+	return strings.Join(list, ", ")
+}
+
+// newMessageID 生成邮件的 Message-ID，域名取自发件人地址
+func newMessageID(from *mail.Address) string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	domain := "localhost"
+	if i := strings.LastIndexByte(from.Address, '@'); i >= 0 {
+		domain = from.Address[i+1:]
+	}
+	return "<" + hex.EncodeToString(b) + "@" + domain + ">"
+}
@@ -0,0 +1,285 @@
+package sender
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/http/httptest"
+	"net/mail"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"notify/internal/config"
+)
+
+// smtpStub 进程内的 SMTP 服务器，记录收到的邮件
+type smtpStub struct {
+	listener  net.Listener
+	tlsConfig *tls.Config
+	reject    map[string]bool
+
+	mu    sync.Mutex
+	auth  string
+	rcpts []string
+	data  string
+	tls   bool
+}
+
+func newSMTPStub(t *testing.T, tlsConfig *tls.Config, implicitTLS bool) *smtpStub {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if implicitTLS {
+		listener = tls.NewListener(listener, tlsConfig)
+	}
+	stub := &smtpStub{listener: listener, tlsConfig: tlsConfig, reject: map[string]bool{}}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go stub.serve(conn, implicitTLS)
+		}
+	}()
+	return stub
+}
+
+func (s *smtpStub) port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+func (s *smtpStub) serve(conn net.Conn, secure bool) {
+	defer conn.Close()
+	tp := textproto.NewConn(conn)
+	tp.PrintfLine("220 stub ESMTP")
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO":
+			tp.PrintfLine("250-stub")
+			if s.tlsConfig != nil && !secure {
+				tp.PrintfLine("250-STARTTLS")
+			}
+			tp.PrintfLine("250 AUTH PLAIN")
+		case "STARTTLS":
+			tp.PrintfLine("220 ready")
+			tlsConn := tls.Server(conn, s.tlsConfig)
+			if tlsConn.Handshake() != nil {
+				return
+			}
+			conn, tp, secure = tlsConn, textproto.NewConn(tlsConn), true
+		case "AUTH":
+			s.mu.Lock()
+			s.auth = arg
+			s.mu.Unlock()
+			tp.PrintfLine("235 ok")
+		case "MAIL":
+			tp.PrintfLine("250 ok")
+		case "RCPT":
+			rcpt := strings.Trim(strings.TrimPrefix(arg, "TO:"), "<>")
+			if s.reject[rcpt] {
+				tp.PrintfLine("550 no such user")
+				continue
+			}
+			s.mu.Lock()
+			s.rcpts = append(s.rcpts, rcpt)
+			s.mu.Unlock()
+			tp.PrintfLine("250 ok")
+		case "DATA":
+			tp.PrintfLine("354 go ahead")
+			data, err := tp.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			s.mu.Lock()
+			s.data, s.tls = string(data), secure
+			s.mu.Unlock()
+			tp.PrintfLine("250 queued")
+		case "QUIT":
+			tp.PrintfLine("221 bye")
+			return
+		default:
+			tp.PrintfLine("502 not implemented")
+		}
+	}
+}
+
+// stubCertificate 借用 httptest 的自签名证书，并写出 CA 文件供发送器信任
+func stubCertificate(t *testing.T) (*tls.Config, string) {
+	t.Helper()
+	server := httptest.NewUnstartedServer(nil)
+	server.StartTLS()
+	t.Cleanup(server.Close)
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(caFile, certPEM, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return &tls.Config{Certificates: server.TLS.Certificates}, caFile
+}
+
+// mailParts 解析邮件，返回各叶子部分解码后的内容
+func mailParts(t *testing.T, data string) (*mail.Message, map[string]string) {
+	t.Helper()
+	msg, err := mail.ReadMessage(strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("Expected valid message, got %v", err)
+	}
+	parts := map[string]string{}
+	var walk func(contentType string, body io.Reader)
+	walk = func(contentType string, body io.Reader) {
+		mediaType, params, _ := mime.ParseMediaType(contentType)
+		if !strings.HasPrefix(mediaType, "multipart/") {
+			// 附件按文件名记录，正文按类型记录
+			if name := params["name"]; name != "" {
+				mediaType = name
+			}
+			b, _ := io.ReadAll(body)
+			parts[mediaType] = string(b)
+			return
+		}
+		reader := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := reader.NextPart()
+			if err != nil {
+				return
+			}
+			var partBody io.Reader = part
+			if part.Header.Get("Content-Transfer-Encoding") == "base64" {
+				partBody = base64.NewDecoder(base64.StdEncoding, part)
+			}
+			walk(part.Header.Get("Content-Type"), partBody)
+		}
+	}
+	walk(msg.Header.Get("Content-Type"), msg.Body)
+	return msg, parts
+}
+
+func TestEmailSend(t *testing.T) {
+	tlsConfig, caFile := stubCertificate(t)
+
+	tests := []struct {
+		name     string
+		security string
+		username string
+	}{
+		{name: "plain with auth", security: EmailSecurityNone, username: "notify@example.com"},
+		{name: "starttls", security: EmailSecuritySTARTTLS, username: "notify@example.com"},
+		{name: "implicit tls without auth", security: EmailSecurityTLS},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := newSMTPStub(t, tlsConfig, tt.security == EmailSecurityTLS)
+			s, err := NewEmailSender(config.EmailConfig{
+				Host:     "127.0.0.1",
+				Port:     stub.port(),
+				Security: tt.security,
+				Username: tt.username,
+				Password: "secret",
+				From:     "Notify <notify@example.com>",
+				CAFile:   caFile,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ctx, receipt := WithReceipt(context.Background())
+			err = s.Send(ctx, "disk <data> at 91%\nplease check", "磁盘告警", map[string]any{
+				"to":          []any{"ops@example.com"},
+				"cc":          "lead@example.com",
+				"bcc":         "audit@example.com",
+				"attachments": []any{map[string]any{"filename": "report.txt", "media_base64": base64.StdEncoding.EncodeToString([]byte("usage report"))}},
+			})
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+
+			stub.mu.Lock()
+			defer stub.mu.Unlock()
+			if want := tt.security != EmailSecurityNone; stub.tls != want {
+				t.Errorf("Expected tls %t, got %t", want, stub.tls)
+			}
+			if (stub.auth != "") != (tt.username != "") {
+				t.Errorf("Expected auth only with username, got %q", stub.auth)
+			}
+			if strings.Join(stub.rcpts, ",") != "ops@example.com,lead@example.com,audit@example.com" {
+				t.Errorf("Expected to, cc and bcc recipients, got %v", stub.rcpts)
+			}
+
+			msg, parts := mailParts(t, stub.data)
+			subject, _ := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+			if subject != "磁盘告警" {
+				t.Errorf("Expected summary as subject, got %q", subject)
+			}
+			if msg.Header.Get("Cc") != "<lead@example.com>" || msg.Header.Get("Bcc") != "" {
+				t.Errorf("Expected cc header and no bcc header, got cc %q bcc %q", msg.Header.Get("Cc"), msg.Header.Get("Bcc"))
+			}
+			if parts["text/plain"] != "disk <data> at 91%\nplease check" {
+				t.Errorf("Expected plain body to be the content, got %q", parts["text/plain"])
+			}
+			if !strings.Contains(parts["text/html"], "<p>disk &lt;data&gt; at 91%<br>please check</p>") {
+				t.Errorf("Expected escaped html body, got %q", parts["text/html"])
+			}
+			if parts["report.txt"] != "usage report" {
+				t.Errorf("Expected report.txt attachment, got %q", parts["report.txt"])
+			}
+			if ids := receipt.IDs(); ids["email:ops@example.com"] != msg.Header.Get("Message-ID") {
+				t.Errorf("Expected message id receipt, got %v", ids)
+			}
+		})
+	}
+}
+
+func TestEmailSendRejected(t *testing.T) {
+	stub := newSMTPStub(t, nil, false)
+	stub.reject["gone@example.com"] = true
+
+	s, err := NewEmailSender(config.EmailConfig{
+		Host:     "127.0.0.1",
+		Port:     stub.port(),
+		Security: EmailSecurityNone,
+		From:     "notify@example.com",
+		To:       []string{"ops@example.com", "gone@example.com"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 部分收件人被拒绝时其余收件人照常收到邮件
+	err = s.Send(context.Background(), "content", "", nil)
+	var partial *PartialError
+	if !errors.As(err, &partial) || len(partial.Failed) != 1 || partial.Failed[0] != "email:gone@example.com" {
+		t.Fatalf("Expected partial error for gone@example.com, got %v", err)
+	}
+
+	// 全部被拒绝时不重试
+	err = s.Send(context.Background(), "content", "", map[string]any{"to": "gone@example.com"})
+	if err == nil || IsRetryable(err) {
+		t.Errorf("Expected permanent error, got %v", err)
+	}
+
+	if err := s.Validate("content", "", map[string]any{"to": "not an address"}); err == nil {
+		t.Errorf("Expected invalid address to be rejected")
+	}
+}
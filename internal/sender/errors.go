@@ -0,0 +1,148 @@
+package sender
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SendError 发送错误，标明该错误是否值得重试
+//
+// RetryAfter 为平台要求的最短重试间隔（如限流响应中的 Retry-After），为 0 时按重试策略退避。
+type SendError struct {
+	Retryable  bool
+	RetryAfter time.Duration
+	Err        error
+}
+
+func (e *SendError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *SendError) Unwrap() error {
+	return e.Err
+}
+
+// Retryable 将错误标记为可重试，如网络错误、服务端错误、限流等
+func Retryable(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &SendError{Retryable: true, Err: err}
+}
+
+// RetryAfter 将错误标记为可重试，并要求至少等待 delay 后再重试
+func RetryAfter(err error, delay time.Duration) error {
+	if err == nil {
+		return nil
+	}
+	return &SendError{Retryable: true, RetryAfter: delay, Err: err}
+}
+
+// RetryDelay 返回错误要求的最短重试间隔，未要求时返回 0
+func RetryDelay(err error) time.Duration {
+	var sendErr *SendError
+	if errors.As(err, &sendErr) {
+		return sendErr.RetryAfter
+	}
+	return 0
+}
+
+// Permanent 将错误标记为不可重试，如接收人无效、消息格式错误等
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &SendError{Retryable: false, Err: err}
+}
+
+// PartialError 消息只送达了部分接收人，Failed 为未送达的接收人
+//
+// 已送达的部分无法撤回，不能重试整条消息。RetryExtra 为空时分发器将消息记为部分送达；
+// 否则使用 RetryExtra 替换消息的 extra，只向未送达的接收人重试。
+type PartialError struct {
+	Failed     []string
+	RetryExtra map[string]any
+}
+
+func (e *PartialError) Error() string {
+	return fmt.Sprintf("partially delivered, failed recipients: %s", strings.Join(e.Failed, ", "))
+}
+
+// Partial 创建部分送达错误
+func Partial(failed []string) error {
+	return &PartialError{Failed: failed}
+}
+
+// PartialRetry 创建可按接收人重试的部分送达错误，retryExtra 只包含未送达的接收人
+func PartialRetry(failed []string, retryExtra map[string]any) error {
+	return &PartialError{Failed: failed, RetryExtra: retryExtra}
+}
+
+// mergePartials 合并拆分发送时各部分的部分送达错误，没有错误时返回 nil
+//
+// 只有各部分的 RetryExtra 完全相同时才保留，否则无法用一组接收人覆盖所有未送达的部分，
+// 合并结果记为部分送达，不再重试。
+func mergePartials(partials []*PartialError) error {
+	if len(partials) == 0 {
+		return nil
+	}
+
+	merged := &PartialError{RetryExtra: partials[0].RetryExtra}
+	seen := make(map[string]bool)
+	for _, partial := range partials {
+		for _, recipient := range partial.Failed {
+			if !seen[recipient] {
+				seen[recipient] = true
+				merged.Failed = append(merged.Failed, recipient)
+			}
+		}
+		if !reflect.DeepEqual(partial.RetryExtra, merged.RetryExtra) {
+			merged.RetryExtra = nil
+		}
+	}
+	return merged
+}
+
+// IsRetryable 判断错误是否可以重试
+//
+// 已分类的错误以发送器的判断为准；未分类的错误（如网络错误）视为可重试，
+// 避免因遗漏分类而丢失消息。
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var sendErr *SendError
+	if errors.As(err, &sendErr) {
+		return sendErr.Retryable
+	}
+	return !errors.Is(err, context.Canceled)
+}
+
+// CheckStatus 根据 HTTP 状态码对响应分类，2xx 返回 nil
+func CheckStatus(resp *http.Response) error {
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+
+	err := fmt.Errorf("unexpected http status: %s", resp.Status)
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+		return RetryAfter(err, parseRetryAfter(resp.Header.Get("Retry-After")))
+	}
+	return Permanent(err)
+}
+
+// parseRetryAfter 解析以秒为单位的 Retry-After 响应头，不支持 HTTP 日期格式
+func parseRetryAfter(value string) time.Duration {
+	seconds, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil || seconds <= 0 {
+		return 0
+	}
+	return time.Duration(seconds * float64(time.Second))
+}
@@ -0,0 +1,145 @@
+package sender
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// ExtraString 读取 extra 中的字符串字段，字段不存在时返回空字符串
+func ExtraString(extra map[string]any, key string) (string, error) {
+	value, ok := extra[key]
+	if !ok || value == nil {
+		return "", nil
+	}
+	s, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("extra.%s must be a string", key)
+	}
+	return s, nil
+}
+
+// ExtraStrings 读取 extra 中的字符串列表，兼容单个字符串或数字
+func ExtraStrings(extra map[string]any, key string) ([]string, error) {
+	value, ok := extra[key]
+	if !ok || value == nil {
+		return nil, nil
+	}
+
+	switch v := value.(type) {
+	case string:
+		if v == "" {
+			return nil, nil
+		}
+		return []string{v}, nil
+	case float64:
+		return []string{strconv.FormatInt(int64(v), 10)}, nil
+	case []string:
+		return v, nil
+	case []any:
+		list := make([]string, 0, len(v))
+		for _, item := range v {
+			switch s := item.(type) {
+			case string:
+				list = append(list, s)
+			case float64:
+				// JSON 中的数字ID按整数处理
+				list = append(list, strconv.FormatInt(int64(s), 10))
+			default:
+				return nil, fmt.Errorf("extra.%s must be a list of strings", key)
+			}
+		}
+		return list, nil
+	default:
+		return nil, fmt.Errorf("extra.%s must be a string or a list of strings", key)
+	}
+}
+
+// ExtraInt 读取 extra 中的整数字段，字段不存在时返回 0
+func ExtraInt(extra map[string]any, key string) (int64, error) {
+	value, ok := extra[key]
+	if !ok || value == nil {
+		return 0, nil
+	}
+	n, ok := toInt(value)
+	if !ok {
+		return 0, fmt.Errorf("extra.%s must be an integer", key)
+	}
+	return n, nil
+}
+
+// ExtraInts 读取 extra 中的整数列表，兼容单个整数和数字字符串
+func ExtraInts(extra map[string]any, key string) ([]int64, error) {
+	value, ok := extra[key]
+	if !ok || value == nil {
+		return nil, nil
+	}
+
+	items, ok := value.([]any)
+	if !ok {
+		items = []any{value}
+	}
+	list := make([]int64, 0, len(items))
+	for _, item := range items {
+		n, ok := toInt(item)
+		if !ok {
+			return nil, fmt.Errorf("extra.%s must be a list of integers", key)
+		}
+		list = append(list, n)
+	}
+	return list, nil
+}
+
+// toInt 将 JSON 解析出的数字或数字字符串转换为整数
+func toInt(value any) (int64, bool) {
+	switch v := value.(type) {
+	case int:
+		return int64(v), true
+	case int64:
+		return v, true
+	case float64:
+		return int64(v), v == float64(int64(v))
+	case string:
+		n, err := strconv.ParseInt(v, 10, 64)
+		return n, err == nil
+	default:
+		return 0, false
+	}
+}
+
+// ExtraBool 读取 extra 中的布尔字段，字段不存在时返回 false
+func ExtraBool(extra map[string]any, key string) (bool, error) {
+	value, ok := extra[key]
+	if !ok || value == nil {
+		return false, nil
+	}
+	b, ok := value.(bool)
+	if !ok {
+		return false, fmt.Errorf("extra.%s must be a boolean", key)
+	}
+	return b, nil
+}
+
+// ExtraObjects 读取 extra 中的对象列表
+func ExtraObjects(extra map[string]any, key string) ([]map[string]any, error) {
+	value, ok := extra[key]
+	if !ok || value == nil {
+		return nil, nil
+	}
+
+	switch v := value.(type) {
+	case []map[string]any:
+		return v, nil
+	case []any:
+		list := make([]map[string]any, 0, len(v))
+		for _, item := range v {
+			obj, ok := item.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("extra.%s must be a list of objects", key)
+			}
+			list = append(list, obj)
+		}
+		return list, nil
+	default:
+		return nil, fmt.Errorf("extra.%s must be a list of objects", key)
+	}
+}
@@ -3,6 +3,7 @@ package factory
 import (
 	"fmt"
 	"notify/internal/config"
+	"notify/internal/parser"
 	"notify/internal/sender"
 	"notify/internal/sender/wechat"
 )
@@ -11,10 +12,38 @@ import (
 func CreateWeChatSender(senderType wechat.WeChatSenderType, config config.WeChatConfig) (sender.Sender, error) {
 	switch senderType {
 	case wechat.SenderTypeWeCom:
-		return wechat.NewWeComSender(config.WeCom), nil
+		return wechat.NewWeComSender(config.WeCom)
+	case wechat.SenderTypeWeComBot:
+		return wechat.NewWeComBotSender(config.WeComBot)
 	case wechat.SenderTypeWxPusher:
-		return wechat.NewWxPusherSender(config.WxPusher), nil
+		return wechat.NewWxPusherSender(config.WxPusher)
 	default:
 		return nil, fmt.Errorf("unsupported WeChat sender type: %s", senderType)
 	}
 }
+
+// CreateChannelSender 根据命名通道的配置创建发送器
+func CreateChannelSender(channel config.ChannelConfig) (sender.Sender, error) {
+	if channel.Name == "" {
+		return nil, fmt.Errorf("channel name is required")
+	}
+
+	switch parser.Platform(channel.Platform) {
+	case parser.PlatformDingTalk:
+		return sender.NewDingTalkSender(channel.DingTalk)
+	case parser.PlatformFeishu:
+		return sender.NewFeishuSender(channel.Feishu)
+	case parser.PlatformSlack:
+		return sender.NewSlackSender(channel.Slack)
+	case parser.PlatformTelegram:
+		return sender.NewTelegramSender(channel.Telegram)
+	case parser.PlatformDiscord:
+		return sender.NewDiscordSender(channel.Discord)
+	case parser.PlatformEmail:
+		return sender.NewEmailSender(channel.Email)
+	case parser.PlatformWeChat:
+		return CreateWeChatSender(wechat.WeChatSenderType(channel.WeChat.SenderType), channel.WeChat)
+	default:
+		return nil, fmt.Errorf("unsupported channel platform: %s", channel.Platform)
+	}
+}
@@ -0,0 +1,375 @@
+package sender
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"notify/internal/config"
+	"notify/pkg/logger"
+
+	"go.uber.org/zap"
+)
+
+// feishuAPIBase 飞书开放平台官方地址
+const feishuAPIBase = "https://open.feishu.cn"
+
+// feishuContentLimit 飞书限制请求体不超过 20KB，为 JSON 结构预留部分空间
+const feishuContentLimit = 18000
+
+// feishuPostLineSize 富文本每行包装为一个段落时额外占用的字节数
+const feishuPostLineSize = len(`[{"tag":"text","text":""}],`)
+
+// 飞书机器人支持的消息类型
+const (
+	FeishuMsgText        = "text"
+	FeishuMsgPost        = "post"
+	FeishuMsgInteractive = "interactive"
+)
+
+// feishuRetryableCodes 飞书返回的可重试错误码（请求过于频繁）
+var feishuRetryableCodes = map[int]bool{
+	9499:  true,
+	11232: true,
+}
+
+// feishuSeverityColors 卡片标题颜色，按 extra["severity"] 选择
+var feishuSeverityColors = map[string]string{
+	"":         "blue",
+	"info":     "blue",
+	"success":  "green",
+	"warning":  "orange",
+	"error":    "red",
+	"critical": "carmine",
+}
+
+type FeishuSender struct {
+	config  config.FeishuConfig
+	client  *http.Client
+	baseURL string
+}
+
+type feishuMessage struct {
+	Timestamp string         `json:"timestamp,omitempty"`
+	Sign      string         `json:"sign,omitempty"`
+	MsgType   string         `json:"msg_type"`
+	Content   *feishuContent `json:"content,omitempty"`
+	Card      *feishuCard    `json:"card,omitempty"`
+}
+
+type feishuContent struct {
+	Text string      `json:"text,omitempty"`
+	Post *feishuPost `json:"post,omitempty"`
+}
+
+type feishuPost struct {
+	ZhCN feishuPostBody `json:"zh_cn"`
+}
+
+type feishuPostBody struct {
+	Title   string                `json:"title"`
+	Content [][]feishuPostElement `json:"content"`
+}
+
+type feishuPostElement struct {
+	Tag  string `json:"tag"`
+	Text string `json:"text,omitempty"`
+	Href string `json:"href,omitempty"`
+}
+
+type feishuCard struct {
+	Config   feishuCardConfig `json:"config"`
+	Header   feishuCardHeader `json:"header"`
+	Elements []map[string]any `json:"elements"`
+}
+
+type feishuCardConfig struct {
+	WideScreenMode bool `json:"wide_screen_mode"`
+}
+
+type feishuCardHeader struct {
+	Title    feishuCardText `json:"title"`
+	Template string         `json:"template"`
+}
+
+type feishuCardText struct {
+	Tag     string `json:"tag"`
+	Content string `json:"content"`
+}
+
+func NewFeishuSender(config config.FeishuConfig) (*FeishuSender, error) {
+	if err := CheckOversize(config.Oversize); err != nil {
+		return nil, err
+	}
+	client, err := NewHTTPClient(config.HTTP)
+	if err != nil {
+		return nil, fmt.Errorf("create feishu http client failed: %w", err)
+	}
+	return &FeishuSender{
+		config:  config,
+		client:  client,
+		baseURL: BaseURL(config.HTTP, feishuAPIBase),
+	}, nil
+}
+
+func (s *FeishuSender) Name() string {
+	return "feishu"
+}
+
+// ContentLimit 正文长度限制，对所有消息类型生效，正文长度按编码后的请求体计算（见 ContentSize）
+//
+// 拆分后的卡片和富文本没有摘要，标题由 Manager 写入 extra["title"]，各部分标题一致。
+func (s *FeishuSender) ContentLimit(extra map[string]any) (int, string) {
+	return feishuContentLimit, s.config.Oversize
+}
+
+// SummaryInTitle 富文本和卡片的摘要作为标题发送，文本消息的摘要写在正文前
+func (s *FeishuSender) SummaryInTitle(extra map[string]any) bool {
+	msgType, _ := ExtraString(extra, "msgtype")
+	return msgType == FeishuMsgPost || msgType == FeishuMsgInteractive
+}
+
+// ContentSize 正文编码到请求体后的字节数，计入 JSON 转义和富文本逐行包装的段落结构
+func (s *FeishuSender) ContentSize(content string, extra map[string]any) int {
+	if msgType, _ := ExtraString(extra, "msgtype"); msgType != FeishuMsgPost {
+		return feishuJSONSize(content)
+	}
+	size := 0
+	for _, line := range strings.Split(content, "\n") {
+		size += feishuJSONSize(line) + feishuPostLineSize
+	}
+	return size
+}
+
+// Validate 校验 extra 中指定的消息类型及其必填字段
+func (s *FeishuSender) Validate(content string, summary string, extra map[string]any) error {
+	_, err := buildFeishuMessage(content, summary, extra)
+	return err
+}
+
+func (s *FeishuSender) Send(ctx context.Context, content string, summary string, extra map[string]any) error {
+	msg, err := buildFeishuMessage(content, summary, extra)
+	if err != nil {
+		return Permanent(err)
+	}
+
+	// 开启签名校验时，在请求体中携带时间戳和签名
+	if s.config.Secret != "" {
+		timestamp := time.Now().Unix()
+		msg.Timestamp = strconv.FormatInt(timestamp, 10)
+		msg.Sign = s.generateSign(timestamp)
+	}
+
+	body, err := feishuEncode(msg)
+	if err != nil {
+		return Permanent(fmt.Errorf("marshal message failed: %w", err))
+	}
+
+	url := fmt.Sprintf("%s/open-apis/bot/v2/hook/%s", s.baseURL, s.config.Token)
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+	if err != nil {
+		return Permanent(fmt.Errorf("create request failed: %w", err))
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return Retryable(fmt.Errorf("send request failed: %w", err))
+	}
+	defer resp.Body.Close()
+
+	if err := CheckStatus(resp); err != nil {
+		return err
+	}
+
+	var result struct {
+		Code int    `json:"code"`
+		Msg  string `json:"msg"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return Retryable(fmt.Errorf("decode response failed: %w", err))
+	}
+
+	if result.Code != 0 {
+		err := fmt.Errorf("send message failed: [%d] %s", result.Code, result.Msg)
+		if feishuRetryableCodes[result.Code] {
+			return Retryable(err)
+		}
+		return Permanent(err)
+	}
+
+	logger.Info("Feishu message sent successfully",
+		zap.String("msg_type", msg.MsgType),
+		zap.String("content", content))
+
+	return nil
+}
+
+// feishuEncode 编码请求体，不转义 <、>、&，避免堆栈等内容膨胀超过请求体限制
+func feishuEncode(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// feishuJSONSize 字符串编码为 JSON 字符串后的字节数，不含两侧引号
+func feishuJSONSize(s string) int {
+	data, err := feishuEncode(s)
+	if err != nil {
+		return len(s)
+	}
+	return len(data) - len(`""`+"\n")
+}
+
+// generateSign 飞书以 "timestamp\nsecret" 为密钥，对空字符串做 HMAC-SHA256 签名
+func (s *FeishuSender) generateSign(timestamp int64) string {
+	stringToSign := fmt.Sprintf("%d\n%s", timestamp, s.config.Secret)
+	h := hmac.New(sha256.New, []byte(stringToSign))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// buildFeishuMessage 根据 extra["msgtype"] 构建飞书消息，缺省为文本消息
+//
+// 各类型使用的 extra 字段：
+//   - post: title（缺省使用摘要）、links[{text, href}]，正文按行拆分为段落
+//   - interactive: title（缺省使用摘要）、severity（info、success、warning、error、critical，决定标题颜色）、
+//     buttons[{text, url}]
+func buildFeishuMessage(content string, summary string, extra map[string]any) (*feishuMessage, error) {
+	msgType, err := ExtraString(extra, "msgtype")
+	if err != nil {
+		return nil, err
+	}
+	title, err := ExtraString(extra, "title")
+	if err != nil {
+		return nil, err
+	}
+	if title == "" {
+		title = summary
+	}
+
+	switch msgType {
+	case "", FeishuMsgText:
+		text := content
+		if summary != "" {
+			text = fmt.Sprintf("【%s】\n\n%s", summary, content)
+		}
+		return &feishuMessage{
+			MsgType: FeishuMsgText,
+			Content: &feishuContent{Text: text},
+		}, nil
+
+	case FeishuMsgPost:
+		body := feishuPostBody{Title: title}
+		for _, line := range strings.Split(content, "\n") {
+			body.Content = append(body.Content, []feishuPostElement{{Tag: "text", Text: line}})
+		}
+		links, err := ExtraObjects(extra, "links")
+		if err != nil {
+			return nil, err
+		}
+		if len(links) > 0 {
+			var paragraph []feishuPostElement
+			for i, item := range links {
+				var link feishuPostElement
+				link.Tag = "a"
+				if link.Text, err = ExtraString(item, "text"); err != nil {
+					return nil, err
+				}
+				if link.Href, err = ExtraString(item, "href"); err != nil {
+					return nil, err
+				}
+				if link.Text == "" || link.Href == "" {
+					return nil, fmt.Errorf("extra.links[%d] requires text and href", i)
+				}
+				paragraph = append(paragraph, link, feishuPostElement{Tag: "text", Text: " "})
+			}
+			body.Content = append(body.Content, paragraph[:len(paragraph)-1])
+		}
+		return &feishuMessage{
+			MsgType: FeishuMsgPost,
+			Content: &feishuContent{Post: &feishuPost{ZhCN: body}},
+		}, nil
+
+	case FeishuMsgInteractive:
+		card, err := buildFeishuCard(title, content, extra)
+		if err != nil {
+			return nil, err
+		}
+		return &feishuMessage{MsgType: FeishuMsgInteractive, Card: card}, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported feishu msgtype: %s", msgType)
+	}
+}
+
+// buildFeishuCard 构建消息卡片，正文使用 lark_md 渲染
+func buildFeishuCard(title string, content string, extra map[string]any) (*feishuCard, error) {
+	if title == "" {
+		return nil, fmt.Errorf("interactive message requires a title or summary")
+	}
+	severity, err := ExtraString(extra, "severity")
+	if err != nil {
+		return nil, err
+	}
+	color, ok := feishuSeverityColors[severity]
+	if !ok {
+		return nil, fmt.Errorf("extra.severity must be one of info, success, warning, error, critical")
+	}
+
+	card := &feishuCard{
+		Config: feishuCardConfig{WideScreenMode: true},
+		Header: feishuCardHeader{
+			Title:    feishuCardText{Tag: "plain_text", Content: title},
+			Template: color,
+		},
+		Elements: []map[string]any{{
+			"tag":  "div",
+			"text": feishuCardText{Tag: "lark_md", Content: content},
+		}},
+	}
+
+	buttons, err := ExtraObjects(extra, "buttons")
+	if err != nil {
+		return nil, err
+	}
+	if len(buttons) > 0 {
+		actions := make([]map[string]any, 0, len(buttons))
+		for i, item := range buttons {
+			text, err := ExtraString(item, "text")
+			if err != nil {
+				return nil, err
+			}
+			url, err := ExtraString(item, "url")
+			if err != nil {
+				return nil, err
+			}
+			if text == "" || url == "" {
+				return nil, fmt.Errorf("extra.buttons[%d] requires text and url", i)
+			}
+			buttonType := "default"
+			if i == 0 {
+				buttonType = "primary"
+			}
+			actions = append(actions, map[string]any{
+				"tag":  "button",
+				"text": feishuCardText{Tag: "plain_text", Content: text},
+				"url":  url,
+				"type": buttonType,
+			})
+		}
+		card.Elements = append(card.Elements, map[string]any{"tag": "action", "actions": actions})
+	}
+	return card, nil
+}
@@ -0,0 +1,180 @@
+package sender
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"notify/internal/config"
+	"notify/internal/parser"
+)
+
+func TestFeishuSend(t *testing.T) {
+	tests := []struct {
+		name      string
+		extra     map[string]any
+		wantType  string
+		wantColor string
+		wantErr   bool
+	}{
+		{name: "text", wantType: FeishuMsgText},
+		{
+			name:     "post with links",
+			extra:    map[string]any{"msgtype": "post", "links": []any{map[string]any{"text": "runbook", "href": "https://example.com"}}},
+			wantType: FeishuMsgPost,
+		},
+		{
+			name:      "critical card",
+			extra:     map[string]any{"msgtype": "interactive", "severity": "critical", "buttons": []any{map[string]any{"text": "查看", "url": "https://example.com"}}},
+			wantType:  FeishuMsgInteractive,
+			wantColor: "carmine",
+		},
+		{
+			name:      "default card color",
+			extra:     map[string]any{"msgtype": "interactive"},
+			wantType:  FeishuMsgInteractive,
+			wantColor: "blue",
+		},
+		{name: "unknown severity", extra: map[string]any{"msgtype": "interactive", "severity": "fatal"}, wantErr: true},
+		{name: "unknown msgtype", extra: map[string]any{"msgtype": "image"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sent feishuMessage
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/open-apis/bot/v2/hook/hook_token" {
+					t.Errorf("Unexpected request path %s", r.URL.Path)
+				}
+				json.NewDecoder(r.Body).Decode(&sent)
+				w.Write([]byte(`{"code":0,"msg":"success","data":{}}`))
+			}))
+			defer server.Close()
+
+			s, err := NewFeishuSender(config.FeishuConfig{
+				Token:  "hook_token",
+				Secret: "secret",
+				HTTP:   config.HTTPConfig{BaseURL: server.URL},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = s.Send(context.Background(), "磁盘使用率 95%", "磁盘告警", tt.extra)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+
+			if sent.MsgType != tt.wantType {
+				t.Errorf("Expected msg_type %s, got %s", tt.wantType, sent.MsgType)
+			}
+			timestamp, _ := strconv.ParseInt(sent.Timestamp, 10, 64)
+			if sent.Sign == "" || sent.Sign != s.generateSign(timestamp) {
+				t.Errorf("Expected valid sign for timestamp %s, got %q", sent.Timestamp, sent.Sign)
+			}
+			if tt.wantColor != "" && (sent.Card == nil || sent.Card.Header.Template != tt.wantColor) {
+				t.Errorf("Expected card color %s, got %+v", tt.wantColor, sent.Card)
+			}
+		})
+	}
+}
+
+// TestFeishuSendOversizeCard 只有摘要的超长卡片拆分后，每张卡片都以摘要为标题
+func TestFeishuSendOversizeCard(t *testing.T) {
+	var titles []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var sent feishuMessage
+		json.NewDecoder(r.Body).Decode(&sent)
+		if sent.Card != nil {
+			titles = append(titles, sent.Card.Header.Title.Content)
+		}
+		w.Write([]byte(`{"code":0,"msg":"success","data":{}}`))
+	}))
+	defer server.Close()
+
+	s, err := NewFeishuSender(config.FeishuConfig{Token: "hook_token", HTTP: config.HTTPConfig{BaseURL: server.URL}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mgr := NewManager()
+	mgr.Register(parser.PlatformFeishu, s)
+
+	err = mgr.Send(context.Background(), &parser.Message{
+		Platform: parser.PlatformFeishu,
+		Content:  strings.Repeat("**node-1** cpu 98%\n", 2500),
+		Summary:  "CPU 告警",
+		Extra:    map[string]any{"msgtype": "interactive", "severity": "error"},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(titles) != 3 {
+		t.Fatalf("Expected 3 cards, got %d", len(titles))
+	}
+	for i, title := range titles {
+		if title != "CPU 告警" {
+			t.Errorf("Expected summary as title of card %d, got %q", i+1, title)
+		}
+	}
+}
+
+func TestFeishuSendOversizePost(t *testing.T) {
+	var sizes []int
+	var lines int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		sizes = append(sizes, len(body))
+		var sent feishuMessage
+		json.Unmarshal(body, &sent)
+		if sent.Content != nil && sent.Content.Post != nil {
+			for _, paragraph := range sent.Content.Post.ZhCN.Content {
+				if strings.Contains(paragraph[0].Text, "Service.java") {
+					lines++
+				}
+			}
+		}
+		w.Write([]byte(`{"code":0,"msg":"success","data":{}}`))
+	}))
+	defer server.Close()
+
+	s, err := NewFeishuSender(config.FeishuConfig{Token: "hook_token", HTTP: config.HTTPConfig{BaseURL: server.URL}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mgr := NewManager()
+	mgr.Register(parser.PlatformFeishu, s)
+
+	// 堆栈行短且含 <、>、"，逐行包装和转义后请求体远大于原文
+	trace := strings.Repeat("\tat <init>(\"Service.java\":42) & more\n\n", 1000)
+	err = mgr.Send(context.Background(), &parser.Message{
+		Platform: parser.PlatformFeishu,
+		Content:  trace,
+		Summary:  "服务异常",
+		Extra:    map[string]any{"msgtype": "post"},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(sizes) < 2 {
+		t.Fatalf("Expected content to be split, got %d requests", len(sizes))
+	}
+	for i, size := range sizes {
+		if size > 20*1024 {
+			t.Errorf("Request %d is %d bytes, exceeds 20KB", i+1, size)
+		}
+	}
+	if lines != 1000 {
+		t.Errorf("Expected 1000 stack lines across parts, got %d", lines)
+	}
+}
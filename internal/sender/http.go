@@ -0,0 +1,98 @@
+package sender
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"notify/internal/config"
+)
+
+// defaultHTTPTimeout 未配置超时时间时的默认值
+const defaultHTTPTimeout = 10 * time.Second
+
+// transportKey 代理和 CA 配置相同的客户端共享同一个 Transport
+type transportKey struct {
+	proxy  string
+	caFile string
+}
+
+var (
+	transportsMu sync.Mutex
+	transports   = make(map[transportKey]*http.Transport)
+)
+
+// NewHTTPClient 根据配置创建 HTTP 客户端
+//
+// 代理和 CA 配置相同的发送器共享同一个 Transport，以复用连接池。
+func NewHTTPClient(cfg config.HTTPConfig) (*http.Client, error) {
+	transport, err := sharedTransport(transportKey{proxy: cfg.Proxy, caFile: cfg.CAFile})
+	if err != nil {
+		return nil, err
+	}
+
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = defaultHTTPTimeout
+	}
+	return &http.Client{Transport: transport, Timeout: timeout}, nil
+}
+
+// BaseURL 返回配置的接口地址，未配置时使用平台官方地址
+func BaseURL(cfg config.HTTPConfig, defaultURL string) string {
+	if cfg.BaseURL == "" {
+		return defaultURL
+	}
+	return strings.TrimRight(cfg.BaseURL, "/")
+}
+
+func sharedTransport(key transportKey) (*http.Transport, error) {
+	transportsMu.Lock()
+	defer transportsMu.Unlock()
+
+	if transport, ok := transports[key]; ok {
+		return transport, nil
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if key.proxy != "" {
+		proxyURL, err := url.Parse(key.proxy)
+		if err != nil || (proxyURL.Scheme != "http" && proxyURL.Scheme != "https") || proxyURL.Host == "" {
+			return nil, fmt.Errorf("invalid proxy url: %s", key.proxy)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+	if key.caFile != "" {
+		pool, err := loadCertPool(key.caFile)
+		if err != nil {
+			return nil, err
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool}
+	}
+
+	transports[key] = transport
+	return transport, nil
+}
+
+// loadCertPool 在系统证书的基础上追加 CA 证书
+func loadCertPool(caFile string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("read ca file failed: %w", err)
+	}
+
+	pool, err := x509.SystemCertPool()
+	if err != nil || pool == nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in ca file: %s", caFile)
+	}
+	return pool, nil
+}
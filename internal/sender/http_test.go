@@ -0,0 +1,62 @@
+package sender
+
+import (
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"notify/internal/config"
+)
+
+func TestNewHTTPClient(t *testing.T) {
+	// 相同代理和 CA 配置的客户端共享 Transport
+	a, err := NewHTTPClient(config.HTTPConfig{Proxy: "http://proxy.local:3128"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := NewHTTPClient(config.HTTPConfig{Proxy: "http://proxy.local:3128", Timeout: time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Transport != b.Transport {
+		t.Error("Expected clients with the same proxy to share a transport")
+	}
+	if a.Timeout != defaultHTTPTimeout || b.Timeout != time.Second {
+		t.Errorf("Expected timeouts %v and %v, got %v and %v", defaultHTTPTimeout, time.Second, a.Timeout, b.Timeout)
+	}
+
+	if _, err := NewHTTPClient(config.HTTPConfig{Proxy: "proxy.local:3128"}); err == nil {
+		t.Error("Expected error for proxy without scheme, got nil")
+	}
+	if _, err := NewHTTPClient(config.HTTPConfig{CAFile: filepath.Join(t.TempDir(), "missing.pem")}); err == nil {
+		t.Error("Expected error for missing ca file, got nil")
+	}
+}
+
+func TestNewHTTPClientCAFile(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	// 将测试服务器的自签名证书写入 CA 文件
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	data := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(caFile, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	client, err := NewHTTPClient(config.HTTPConfig{CAFile: caFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Expected request trusted by custom ca to succeed, got %v", err)
+	}
+	resp.Body.Close()
+}
@@ -0,0 +1,51 @@
+package sender
+
+import (
+	"context"
+	"sync"
+)
+
+// Receipt 记录一次发送中平台返回的消息ID，键为接收人，值为平台侧的消息ID
+//
+// 发送器通过 RecordReceipt 写入，调用方在发送完成后读取，便于之后查询或撤回消息。
+type Receipt struct {
+	mu  sync.Mutex
+	ids map[string]string
+}
+
+type receiptKey struct{}
+
+// WithReceipt 返回携带回执的 context
+func WithReceipt(ctx context.Context) (context.Context, *Receipt) {
+	receipt := &Receipt{}
+	return context.WithValue(ctx, receiptKey{}, receipt), receipt
+}
+
+// RecordReceipt 记录接收人对应的平台消息ID，context 中没有回执时忽略
+func RecordReceipt(ctx context.Context, recipient string, id string) {
+	receipt, ok := ctx.Value(receiptKey{}).(*Receipt)
+	if !ok {
+		return
+	}
+
+	receipt.mu.Lock()
+	defer receipt.mu.Unlock()
+	if receipt.ids == nil {
+		receipt.ids = make(map[string]string)
+	}
+	receipt.ids[recipient] = id
+}
+
+// IDs 返回已记录的消息ID，没有记录时返回 nil
+func (r *Receipt) IDs() map[string]string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.ids) == 0 {
+		return nil
+	}
+	ids := make(map[string]string, len(r.ids))
+	for recipient, id := range r.ids {
+		ids[recipient] = id
+	}
+	return ids
+}
@@ -3,6 +3,7 @@ package sender
 import (
 	"context"
 	"errors"
+	"fmt"
 
 	"notify/internal/parser"
 )
@@ -11,13 +12,36 @@ type Sender interface {
 	Send(ctx context.Context, content string, summary string, extra map[string]any) error
 }
 
+// Validator 可选接口，在接收请求时校验消息，使格式错误的请求直接返回 400
+type Validator interface {
+	Validate(content string, summary string, extra map[string]any) error
+}
+
+// Closer 可选接口，释放发送器持有的资源（如限流器的后台协程）
+type Closer interface {
+	Close() error
+}
+
+// Namer 可选接口，返回发送器名称，用于状态查询和日志
+type Namer interface {
+	Name() string
+}
+
 type Manager struct {
-	senders map[parser.Platform]Sender
+	senders  map[parser.Platform]Sender
+	channels map[string]channel
+}
+
+// channel 命名通道及其所属平台
+type channel struct {
+	platform parser.Platform
+	sender   Sender
 }
 
 func NewManager() *Manager {
 	return &Manager{
-		senders: make(map[parser.Platform]Sender),
+		senders:  make(map[parser.Platform]Sender),
+		channels: make(map[string]channel),
 	}
 }
 
@@ -25,11 +49,168 @@ func (m *Manager) Register(platform parser.Platform, sender Sender) {
 	m.senders[platform] = sender
 }
 
+// RegisterChannel 注册命名通道，消息通过 channel 字段指定通道名
+func (m *Manager) RegisterChannel(platform parser.Platform, name string, sender Sender) error {
+	if _, ok := m.channels[name]; ok {
+		return fmt.Errorf("duplicate channel: %s", name)
+	}
+	m.channels[name] = channel{platform: platform, sender: sender}
+	return nil
+}
+
+// lookup 返回处理该消息的发送器，未指定通道时使用平台的默认发送器
+func (m *Manager) lookup(msg *parser.Message) (Sender, error) {
+	if msg.Channel == "" {
+		sender, ok := m.senders[msg.Platform]
+		if !ok {
+			return nil, errors.New("unsupported platform")
+		}
+		return sender, nil
+	}
+
+	ch, ok := m.channels[msg.Channel]
+	if !ok {
+		return nil, fmt.Errorf("unknown channel: %s", msg.Channel)
+	}
+	if ch.platform != msg.Platform {
+		return nil, fmt.Errorf("channel %s does not belong to platform %s", msg.Channel, msg.Platform)
+	}
+	return ch.sender, nil
+}
+
+// Name 返回处理该消息的发送器名称，未实现 Namer 时使用平台名，命名通道附加通道名
+func (m *Manager) Name(msg *parser.Message) string {
+	name := string(msg.Platform)
+	if sender, err := m.lookup(msg); err == nil {
+		if namer, ok := sender.(Namer); ok {
+			name = namer.Name()
+		}
+	}
+	if msg.Channel != "" {
+		name += "#" + msg.Channel
+	}
+	return name
+}
+
+// Validate 校验消息的通道，并使用对应的发送器校验内容
+//
+// 平台未注册默认发送器时不做校验，由发送时返回错误；发送器未实现 Validator 时只校验通道。
+func (m *Manager) Validate(msg *parser.Message) error {
+	sender, err := m.lookup(msg)
+	if err != nil {
+		if msg.Channel == "" {
+			return nil
+		}
+		return err
+	}
+	if validator, ok := sender.(Validator); ok {
+		return validator.Validate(msg.Content, msg.Summary, msg.Extra)
+	}
+	return nil
+}
+
 func (m *Manager) Send(ctx context.Context, msg *parser.Message) error {
-	sender, ok := m.senders[msg.Platform]
+	sender, err := m.lookup(msg)
+	if err != nil {
+		return Permanent(err)
+	}
+
+	limiter, ok := sender.(Limiter)
 	if !ok {
-		return errors.New("unsupported platform")
+		return sender.Send(ctx, msg.Content, msg.Summary, msg.Extra)
+	}
+
+	// 超长正文按发送器的策略拆分或截断，摘要只随第一部分发送。
+	// 中途失败时整条消息重试，已发送的部分会重复发送；部分接收人未送达时继续发送剩余部分，
+	// 已送达的接收人仍能收到完整内容。
+	limit, policy := limiter.ContentLimit(msg.Extra)
+	var size func(string) int
+	if sizer, ok := sender.(Sizer); ok {
+		size = func(s string) int { return sizer.ContentSize(s, msg.Extra) }
+	}
+	// 摘要作为独立标题发送时不占用正文长度
+	reserve := msg.Summary
+	if titler, ok := sender.(Titler); ok && titler.SummaryInTitle(msg.Extra) {
+		reserve = ""
+	}
+	parts := FitContent(msg.Content, reserve, limit, policy, size)
+	extra := msg.Extra
+	if len(parts) > 1 {
+		extra = partExtra(msg.Extra, msg.Summary)
 	}
+	var partials []*PartialError
+	for i, part := range parts {
+		summary := msg.Summary
+		if i > 0 {
+			summary = ""
+		}
+		err := sender.Send(withPart(ctx, i), part, summary, extra)
+		if err == nil {
+			continue
+		}
+		var partial *PartialError
+		if len(parts) > 1 && errors.As(err, &partial) {
+			partials = append(partials, partial)
+			continue
+		}
+		if len(parts) > 1 {
+			return fmt.Errorf("send part %d/%d failed: %w", i+1, len(parts), err)
+		}
+		return err
+	}
+	return mergePartials(partials)
+}
 
-	return sender.Send(ctx, msg.Content, msg.Summary, msg.Extra)
+// partExtra 返回拆分后各部分使用的 extra
+//
+// 钉钉 markdown、企业微信 textcard、飞书卡片等类型的标题缺省取摘要，而后续部分不带摘要，
+// 因此未指定 title 时将摘要写入 title，保证每一部分都有相同的标题。
+func partExtra(extra map[string]any, summary string) map[string]any {
+	if summary == "" {
+		return extra
+	}
+	if title, _ := ExtraString(extra, "title"); title != "" {
+		return extra
+	}
+	copied := make(map[string]any, len(extra)+1)
+	for k, v := range extra {
+		copied[k] = v
+	}
+	copied["title"] = summary
+	return copied
+}
+
+type partKey struct{}
+
+// withPart 记录当前发送的是拆分后的第几部分
+func withPart(ctx context.Context, index int) context.Context {
+	return context.WithValue(ctx, partKey{}, index)
+}
+
+// PartIndex 返回当前发送的是拆分后的第几部分（从 0 开始），正文未拆分时为 0
+//
+// 发送器可以据此只在第一部分提醒接收人，避免每一部分都重复提醒。
+func PartIndex(ctx context.Context) int {
+	index, _ := ctx.Value(partKey{}).(int)
+	return index
+}
+
+// Close 关闭所有实现了 Closer 的发送器
+func (m *Manager) Close() error {
+	var errs []error
+	for platform, sender := range m.senders {
+		if closer, ok := sender.(Closer); ok {
+			if err := closer.Close(); err != nil {
+				errs = append(errs, fmt.Errorf("close %s sender failed: %w", platform, err))
+			}
+		}
+	}
+	for name, ch := range m.channels {
+		if closer, ok := ch.sender.(Closer); ok {
+			if err := closer.Close(); err != nil {
+				errs = append(errs, fmt.Errorf("close channel %s sender failed: %w", name, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
 }
@@ -0,0 +1,95 @@
+package sender
+
+import (
+	"context"
+	"testing"
+
+	"notify/internal/parser"
+)
+
+// namedSender 记录收到的消息内容
+type namedSender struct {
+	name string
+	sent []string
+}
+
+func (s *namedSender) Name() string {
+	return s.name
+}
+
+func (s *namedSender) Send(ctx context.Context, content string, summary string, extra map[string]any) error {
+	s.sent = append(s.sent, content)
+	return nil
+}
+
+func TestManagerChannels(t *testing.T) {
+	defaultSender := &namedSender{name: "dingtalk"}
+	opsSender := &namedSender{name: "dingtalk"}
+
+	mgr := NewManager()
+	mgr.Register(parser.PlatformDingTalk, defaultSender)
+	if err := mgr.RegisterChannel(parser.PlatformDingTalk, "ops", opsSender); err != nil {
+		t.Fatal(err)
+	}
+	if err := mgr.RegisterChannel(parser.PlatformDingTalk, "ops", opsSender); err == nil {
+		t.Error("Expected error for duplicate channel, got nil")
+	}
+
+	tests := []struct {
+		name     string
+		msg      *parser.Message
+		want     *namedSender
+		wantName string
+		wantErr  bool
+	}{
+		{
+			name:     "default sender",
+			msg:      &parser.Message{Platform: parser.PlatformDingTalk, Content: "default"},
+			want:     defaultSender,
+			wantName: "dingtalk",
+		},
+		{
+			name:     "named channel",
+			msg:      &parser.Message{Platform: parser.PlatformDingTalk, Channel: "ops", Content: "ops"},
+			want:     opsSender,
+			wantName: "dingtalk#ops",
+		},
+		{
+			name:    "unknown channel",
+			msg:     &parser.Message{Platform: parser.PlatformDingTalk, Channel: "payments", Content: "x"},
+			wantErr: true,
+		},
+		{
+			name:    "channel of another platform",
+			msg:     &parser.Message{Platform: parser.PlatformWeChat, Channel: "ops", Content: "x"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := mgr.Validate(tt.msg)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Expected validation error, got nil")
+				}
+				if err := mgr.Send(context.Background(), tt.msg); IsRetryable(err) {
+					t.Errorf("Expected permanent error, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if err := mgr.Send(context.Background(), tt.msg); err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if n := len(tt.want.sent); n == 0 || tt.want.sent[n-1] != tt.msg.Content {
+				t.Errorf("Expected %s to receive %q, got %v", tt.want.name, tt.msg.Content, tt.want.sent)
+			}
+			if name := mgr.Name(tt.msg); name != tt.wantName {
+				t.Errorf("Expected sender name %s, got %s", tt.wantName, name)
+			}
+		})
+	}
+}
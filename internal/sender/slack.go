@@ -0,0 +1,219 @@
+package sender
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"notify/internal/config"
+	"notify/pkg/logger"
+
+	"go.uber.org/zap"
+)
+
+// slackAPIBase Slack Web API 官方地址
+const slackAPIBase = "https://slack.com"
+
+const (
+	slackHeaderLimit  = 150   // header 块文本的最大字符数
+	slackSectionLimit = 3000  // section 块文本的最大字符数
+	slackContentLimit = 36000 // 单条消息正文上限，保证 section 块数量不超过 50
+)
+
+// slackRetryableErrors chat.postMessage 返回的可重试错误
+var slackRetryableErrors = map[string]bool{
+	"ratelimited":         true,
+	"internal_error":      true,
+	"fatal_error":         true,
+	"service_unavailable": true,
+	"request_timeout":     true,
+}
+
+type SlackSender struct {
+	config  config.SlackConfig
+	client  *http.Client
+	baseURL string
+}
+
+type slackMessage struct {
+	Channel string       `json:"channel,omitempty"`
+	Text    string       `json:"text"` // 通知和不支持 Block Kit 的客户端中显示的文本
+	Blocks  []slackBlock `json:"blocks"`
+}
+
+type slackBlock struct {
+	Type string     `json:"type"`
+	Text *slackText `json:"text,omitempty"`
+}
+
+type slackText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+func NewSlackSender(config config.SlackConfig) (*SlackSender, error) {
+	if err := CheckOversize(config.Oversize); err != nil {
+		return nil, err
+	}
+	client, err := NewHTTPClient(config.HTTP)
+	if err != nil {
+		return nil, fmt.Errorf("create slack http client failed: %w", err)
+	}
+	return &SlackSender{
+		config:  config,
+		client:  client,
+		baseURL: BaseURL(config.HTTP, slackAPIBase),
+	}, nil
+}
+
+func (s *SlackSender) Name() string {
+	return "slack"
+}
+
+// ContentLimit 正文长度限制
+func (s *SlackSender) ContentLimit(extra map[string]any) (int, string) {
+	return slackContentLimit, s.config.Oversize
+}
+
+// SummaryInTitle 摘要作为 header 块发送
+func (s *SlackSender) SummaryInTitle(extra map[string]any) bool {
+	return true
+}
+
+// Validate 校验频道，使用机器人 token 发送时必须指定频道，使用 webhook 时不能指定频道
+func (s *SlackSender) Validate(content string, summary string, extra map[string]any) error {
+	_, err := s.buildMessage(content, summary, extra)
+	return err
+}
+
+func (s *SlackSender) Send(ctx context.Context, content string, summary string, extra map[string]any) error {
+	msg, err := s.buildMessage(content, summary, extra)
+	if err != nil {
+		return Permanent(err)
+	}
+
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return Permanent(fmt.Errorf("marshal message failed: %w", err))
+	}
+
+	if s.config.BotToken != "" {
+		return s.postMessage(ctx, msg.Channel, body)
+	}
+	if s.config.WebhookURL == "" {
+		return Permanent(fmt.Errorf("slack webhook_url or bot_token is required"))
+	}
+	return s.postWebhook(ctx, body)
+}
+
+// postWebhook 通过 incoming webhook 发送，成功时返回纯文本 "ok"
+func (s *SlackSender) postWebhook(ctx context.Context, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, "POST", s.config.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return Permanent(fmt.Errorf("create request failed: %w", err))
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return Retryable(fmt.Errorf("send request failed: %w", err))
+	}
+	defer resp.Body.Close()
+
+	if err := CheckStatus(resp); err != nil {
+		// webhook 的错误原因（如 channel_not_found）在响应体中
+		reason, _ := io.ReadAll(io.LimitReader(resp.Body, 256))
+		return fmt.Errorf("%w: %s", err, bytes.TrimSpace(reason))
+	}
+
+	logger.Info("Slack webhook message sent successfully")
+	return nil
+}
+
+// postMessage 通过 chat.postMessage 发送，记录返回的消息时间戳作为回执
+func (s *SlackSender) postMessage(ctx context.Context, channel string, body []byte) error {
+	url := s.baseURL + "/api/chat.postMessage"
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+	if err != nil {
+		return Permanent(fmt.Errorf("create request failed: %w", err))
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	req.Header.Set("Authorization", "Bearer "+s.config.BotToken)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return Retryable(fmt.Errorf("send request failed: %w", err))
+	}
+	defer resp.Body.Close()
+
+	if err := CheckStatus(resp); err != nil {
+		return err
+	}
+
+	var result struct {
+		OK      bool   `json:"ok"`
+		Error   string `json:"error"`
+		Channel string `json:"channel"`
+		TS      string `json:"ts"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return Retryable(fmt.Errorf("decode response failed: %w", err))
+	}
+
+	if !result.OK {
+		err := fmt.Errorf("send message failed: %s", result.Error)
+		if slackRetryableErrors[result.Error] {
+			return Retryable(err)
+		}
+		return Permanent(err)
+	}
+
+	RecordReceipt(ctx, "channel:"+result.Channel, result.TS)
+	logger.Info("Slack message sent successfully",
+		zap.String("channel", channel),
+		zap.String("ts", result.TS))
+
+	return nil
+}
+
+// buildMessage 将摘要映射为 header 块，正文映射为 mrkdwn section 块
+//
+// 频道取 extra["channel"]，未指定时使用配置的默认频道。
+func (s *SlackSender) buildMessage(content string, summary string, extra map[string]any) (*slackMessage, error) {
+	channel, err := ExtraString(extra, "channel")
+	if err != nil {
+		return nil, err
+	}
+	if s.config.BotToken == "" {
+		// incoming webhook 固定发送到创建时选择的频道，忽略请求中的频道
+		if channel != "" {
+			return nil, fmt.Errorf("extra.channel requires slack bot_token, incoming webhooks always post to their own channel")
+		}
+	} else {
+		if channel == "" {
+			channel = s.config.Channel
+		}
+		if channel == "" {
+			return nil, fmt.Errorf("slack message requires extra.channel when no default channel is configured")
+		}
+	}
+
+	msg := &slackMessage{Channel: channel, Text: content}
+	if summary != "" {
+		msg.Text = summary
+		msg.Blocks = append(msg.Blocks, slackBlock{
+			Type: "header",
+			Text: &slackText{Type: "plain_text", Text: summary[:runeBoundary(summary, slackHeaderLimit)]},
+		})
+	}
+	for _, chunk := range SplitChunks(content, slackSectionLimit) {
+		msg.Blocks = append(msg.Blocks, slackBlock{
+			Type: "section",
+			Text: &slackText{Type: "mrkdwn", Text: chunk},
+		})
+	}
+	return msg, nil
+}
@@ -0,0 +1,125 @@
+package sender
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"notify/internal/config"
+)
+
+func TestSlackSendWebhook(t *testing.T) {
+	var sent slackMessage
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&sent)
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	s, err := NewSlackSender(config.SlackConfig{WebhookURL: server.URL + "/services/T000/B000/XXX"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// webhook 固定发送到创建时选择的频道，请求中指定频道时拒绝
+	if err := s.Validate("hello", "", map[string]any{"channel": "#ops"}); err == nil {
+		t.Error("Expected error for extra.channel on incoming webhook")
+	}
+
+	content := strings.Repeat("frame\n", 600)
+	if err := s.Send(context.Background(), content, "Build failed", nil); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// 摘要为 header 块，超过 3000 字节的正文拆分为多个 section 块
+	if len(sent.Blocks) != 3 || sent.Blocks[0].Type != "header" || sent.Blocks[1].Type != "section" {
+		t.Fatalf("Expected header and 2 section blocks, got %+v", sent.Blocks)
+	}
+	if sent.Blocks[0].Text.Text != "Build failed" || sent.Text != "Build failed" {
+		t.Errorf("Expected summary in header and fallback text, got %q and %q", sent.Blocks[0].Text.Text, sent.Text)
+	}
+	if sent.Channel != "" {
+		t.Errorf("Expected no channel for incoming webhook, got %s", sent.Channel)
+	}
+}
+
+func TestSlackSendPostMessage(t *testing.T) {
+	tests := []struct {
+		name          string
+		response      string
+		extra         map[string]any
+		wantChannel   string
+		wantErr       bool
+		wantRetryable bool
+	}{
+		{
+			name:        "default channel",
+			response:    `{"ok":true,"channel":"C123","ts":"1700000000.000100"}`,
+			wantChannel: "#alerts",
+		},
+		{
+			name:        "channel from request",
+			response:    `{"ok":true,"channel":"C456","ts":"1700000000.000200"}`,
+			extra:       map[string]any{"channel": "#payments"},
+			wantChannel: "#payments",
+		},
+		{
+			name:     "channel not found",
+			response: `{"ok":false,"error":"channel_not_found"}`,
+			wantErr:  true,
+		},
+		{
+			name:          "rate limited",
+			response:      `{"ok":false,"error":"ratelimited"}`,
+			wantErr:       true,
+			wantRetryable: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sent slackMessage
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/api/chat.postMessage" || r.Header.Get("Authorization") != "Bearer xoxb-test" {
+					t.Errorf("Unexpected request %s with authorization %q", r.URL.Path, r.Header.Get("Authorization"))
+				}
+				json.NewDecoder(r.Body).Decode(&sent)
+				w.Write([]byte(tt.response))
+			}))
+			defer server.Close()
+
+			s, err := NewSlackSender(config.SlackConfig{
+				BotToken: "xoxb-test",
+				Channel:  "#alerts",
+				HTTP:     config.HTTPConfig{BaseURL: server.URL},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ctx, receipt := WithReceipt(context.Background())
+			err = s.Send(ctx, "deploy finished", "", tt.extra)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Expected error, got nil")
+				}
+				if IsRetryable(err) != tt.wantRetryable {
+					t.Errorf("Expected retryable %v, got %v", tt.wantRetryable, IsRetryable(err))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if sent.Channel != tt.wantChannel {
+				t.Errorf("Expected channel %s, got %s", tt.wantChannel, sent.Channel)
+			}
+			if len(receipt.IDs()) != 1 {
+				t.Errorf("Expected message ts receipt, got %v", receipt.IDs())
+			}
+		})
+	}
+}
@@ -0,0 +1,177 @@
+package sender
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// 正文超过平台长度限制时的处理方式
+const (
+	OversizeSplit    = "split"    // 拆分为多条带编号的消息，默认
+	OversizeTruncate = "truncate" // 截断并追加提示
+)
+
+const (
+	// partMarkerMax 为 "(i/n)\n" 编号预留空间时按最长的编号计算
+	partMarkerMax = "(999/999)\n"
+	// summaryReserve 为摘要两侧的括号和换行预留的字节数
+	summaryReserve = 16
+	// truncateMarker 截断后追加的提示
+	truncateMarker = "\n...(内容过长，已截断)"
+)
+
+// Limiter 可选接口，声明平台对正文长度的限制
+type Limiter interface {
+	// ContentLimit 返回该消息正文的最大字节数和超长时的处理方式，limit 为 0 表示不限制
+	ContentLimit(extra map[string]any) (limit int, policy string)
+}
+
+// Sizer 可选接口，平台按编码后的请求体计算长度时实现（如 JSON 转义、逐行包装），
+// 返回正文计入长度限制的字节数，未实现时按原文字节数计算
+type Sizer interface {
+	ContentSize(content string, extra map[string]any) int
+}
+
+// Titler 可选接口，摘要作为独立的标题字段发送、不占用正文长度时实现
+type Titler interface {
+	// SummaryInTitle 返回该消息的摘要是否作为标题单独发送
+	SummaryInTitle(extra map[string]any) bool
+}
+
+// CheckOversize 校验配置的超长处理方式
+func CheckOversize(policy string) error {
+	switch policy {
+	case "", OversizeSplit, OversizeTruncate:
+		return nil
+	default:
+		return fmt.Errorf("invalid oversize policy: %s", policy)
+	}
+}
+
+// FitContent 按长度限制处理正文，返回需要依次发送的各部分
+//
+// 摘要只随第一部分发送，但各部分都为摘要预留了空间，保证拆分结果不受摘要长度影响；
+// 预留空间最多为 limit 的一半，过长的摘要不会让正文无法拆分。
+// 摘要作为独立标题发送、不占用正文长度时传入空字符串。
+// size 计算正文计入限制的字节数，为 nil 时按原文字节数计算。
+func FitContent(content string, summary string, limit int, policy string, size func(string) int) []string {
+	if limit <= 0 {
+		return []string{content}
+	}
+	if size == nil {
+		size = byteSize
+	}
+	if summary != "" {
+		limit -= min(len(summary)+summaryReserve, limit/2)
+	}
+	if size(content) <= limit {
+		return []string{content}
+	}
+
+	if policy == OversizeTruncate {
+		return []string{truncateContent(content, limit, size)}
+	}
+	return splitContent(content, limit, size)
+}
+
+// SplitContent 将正文拆分为不超过 limit 字节的多个部分，并加上 "(1/3)" 形式的编号
+//
+// 优先在换行处拆分，一行超长时在 UTF-8 字符边界处拆分。
+func SplitContent(content string, limit int) []string {
+	return splitContent(content, limit, byteSize)
+}
+
+func splitContent(content string, limit int, size func(string) int) []string {
+	budget := limit - size(partMarkerMax)
+	if budget <= 0 || size(content) <= limit {
+		return []string{content}
+	}
+
+	chunks := splitChunks(content, budget, size)
+	parts := make([]string, len(chunks))
+	for i, chunk := range chunks {
+		parts[i] = fmt.Sprintf("(%d/%d)\n%s", i+1, len(chunks), strings.TrimRight(chunk, "\n"))
+	}
+	return parts
+}
+
+// SplitChunks 将正文拆分为不超过 limit 字节的片段，不加编号，用于拆分单条消息内的段落
+func SplitChunks(content string, limit int) []string {
+	return splitChunks(content, limit, byteSize)
+}
+
+func splitChunks(content string, limit int, size func(string) int) []string {
+	if limit <= 0 || size(content) <= limit {
+		return []string{content}
+	}
+
+	var chunks []string
+	for size(content) > limit {
+		n := fitPrefix(content, limit, size)
+		cut := strings.LastIndexByte(content[:n], '\n') + 1
+		if cut == 0 {
+			cut = runeBoundary(content, n)
+		}
+		chunks = append(chunks, content[:cut])
+		content = content[cut:]
+	}
+	if content != "" {
+		chunks = append(chunks, content)
+	}
+	return chunks
+}
+
+// TruncateContent 将正文截断到 limit 字节以内，并追加截断提示
+func TruncateContent(content string, limit int) string {
+	return truncateContent(content, limit, byteSize)
+}
+
+func truncateContent(content string, limit int, size func(string) int) string {
+	if size(content) <= limit {
+		return content
+	}
+	budget := limit - size(truncateMarker)
+	if budget <= 0 {
+		return content[:runeBoundary(content, fitPrefix(content, limit, size))]
+	}
+	return content[:runeBoundary(content, fitPrefix(content, budget, size))] + truncateMarker
+}
+
+// fitPrefix 返回 size 不超过 limit 的最长前缀的字节数
+//
+// 计入限制的字节数不少于原文字节数，按原文计算时直接返回 limit，否则二分查找。
+func fitPrefix(content string, limit int, size func(string) int) int {
+	hi := min(limit, len(content))
+	if size(content[:hi]) <= limit {
+		return hi
+	}
+	lo := 0
+	for lo < hi {
+		mid := (lo + hi + 1) / 2
+		if size(content[:mid]) <= limit {
+			lo = mid
+		} else {
+			hi = mid - 1
+		}
+	}
+	return lo
+}
+
+func byteSize(s string) int {
+	return len(s)
+}
+
+// runeBoundary 返回不超过 n 的最大 UTF-8 字符边界，至少保留一个字符
+func runeBoundary(s string, n int) int {
+	if n >= len(s) {
+		return len(s)
+	}
+	for i := n; i > 0; i-- {
+		if utf8.RuneStart(s[i]) {
+			return i
+		}
+	}
+	_, size := utf8.DecodeRuneInString(s)
+	return size
+}
@@ -0,0 +1,285 @@
+package sender
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"notify/internal/config"
+	"notify/internal/parser"
+)
+
+func TestSplitContent(t *testing.T) {
+	tests := []struct {
+		name      string
+		content   string
+		limit     int
+		wantParts []string
+	}{
+		{
+			name:      "fits",
+			content:   "short",
+			limit:     100,
+			wantParts: []string{"short"},
+		},
+		{
+			name:      "split at line boundaries",
+			content:   "stack-frame-1\nstack-frame-2\nstack-frame-3\n",
+			limit:     len("(999/999)\n") + 30,
+			wantParts: []string{"(1/2)\nstack-frame-1\nstack-frame-2", "(2/2)\nstack-frame-3"},
+		},
+		{
+			name:      "split long line at utf-8 boundary",
+			content:   strings.Repeat("错", 10),
+			limit:     len("(999/999)\n") + 7,
+			wantParts: []string{"(1/5)\n错错", "(2/5)\n错错", "(3/5)\n错错", "(4/5)\n错错", "(5/5)\n错错"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parts := SplitContent(tt.content, tt.limit)
+			if strings.Join(parts, "|") != strings.Join(tt.wantParts, "|") {
+				t.Errorf("Expected parts %q, got %q", tt.wantParts, parts)
+			}
+			for _, part := range parts {
+				if len(part) > tt.limit || !utf8.ValidString(part) {
+					t.Errorf("Expected valid part within %d bytes, got %q", tt.limit, part)
+				}
+			}
+		})
+	}
+}
+
+func TestTruncateContent(t *testing.T) {
+	content := strings.Repeat("错", 100)
+	limit := len(truncateMarker) + 10
+	got := TruncateContent(content, limit)
+	if len(got) > limit || !utf8.ValidString(got) {
+		t.Errorf("Expected valid content within %d bytes, got %q", limit, got)
+	}
+	if !strings.HasSuffix(got, truncateMarker) {
+		t.Errorf("Expected truncate marker, got %q", got)
+	}
+}
+
+func TestFitContentSummary(t *testing.T) {
+	content := strings.Repeat("stack frame\n", 20)
+	const limit = 100
+
+	tests := []struct {
+		name      string
+		summary   string
+		wantParts int
+	}{
+		{name: "no summary", summary: "", wantParts: 3},
+		{name: "summary in body", summary: "panic", wantParts: 4},
+		// 摘要超过限制时最多预留一半空间，正文仍能拆分
+		{name: "summary longer than limit", summary: strings.Repeat("s", 200), wantParts: 7},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parts := FitContent(content, tt.summary, limit, OversizeSplit, nil)
+			if len(parts) != tt.wantParts {
+				t.Fatalf("Expected %d parts, got %d", tt.wantParts, len(parts))
+			}
+			for i, part := range parts {
+				if len(part) > limit {
+					t.Errorf("Expected part %d within %d bytes, got %d", i+1, limit, len(part))
+				}
+			}
+		})
+	}
+}
+
+// limitedSender 正文限制为 limit 字节的发送器，按 errs 依次返回各部分的发送结果
+type limitedSender struct {
+	limit     int
+	policy    string
+	errs      []error
+	contents  []string
+	summaries []string
+}
+
+func (s *limitedSender) ContentLimit(extra map[string]any) (int, string) {
+	return s.limit, s.policy
+}
+
+func (s *limitedSender) Send(ctx context.Context, content string, summary string, extra map[string]any) error {
+	s.contents = append(s.contents, content)
+	s.summaries = append(s.summaries, summary)
+	if i := len(s.contents) - 1; i < len(s.errs) {
+		return s.errs[i]
+	}
+	return nil
+}
+
+func TestManagerSendOversize(t *testing.T) {
+	content := strings.Repeat("stack frame\n", 20)
+
+	tests := []struct {
+		name      string
+		policy    string
+		wantParts int
+	}{
+		{name: "split", policy: OversizeSplit, wantParts: 3},
+		{name: "truncate", policy: OversizeTruncate, wantParts: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &limitedSender{limit: 120, policy: tt.policy}
+			mgr := NewManager()
+			mgr.Register(parser.PlatformDingTalk, fake)
+
+			msg := &parser.Message{Platform: parser.PlatformDingTalk, Content: content, Summary: "panic"}
+			if err := mgr.Send(context.Background(), msg); err != nil {
+				t.Fatal(err)
+			}
+			if len(fake.contents) != tt.wantParts {
+				t.Fatalf("Expected %d parts, got %d", tt.wantParts, len(fake.contents))
+			}
+
+			// 摘要只随第一部分发送
+			for i, summary := range fake.summaries {
+				want := ""
+				if i == 0 {
+					want = "panic"
+				}
+				if summary != want {
+					t.Errorf("Expected summary %q on part %d, got %q", want, i+1, summary)
+				}
+				if len(fake.contents[i])+len(summary)+summaryReserve > fake.limit {
+					t.Errorf("Expected part %d within limit, got %d bytes", i+1, len(fake.contents[i]))
+				}
+			}
+		})
+	}
+}
+
+// titledSender 摘要作为标题单独发送的 limitedSender
+type titledSender struct {
+	limitedSender
+}
+
+func (s *titledSender) SummaryInTitle(extra map[string]any) bool {
+	return true
+}
+
+func TestManagerSendSummaryInTitle(t *testing.T) {
+	fake := &titledSender{limitedSender{limit: 100, policy: OversizeSplit}}
+	mgr := NewManager()
+	mgr.Register(parser.PlatformDingTalk, fake)
+
+	// 摘要不占用正文长度，拆分结果与没有摘要时相同
+	msg := &parser.Message{Platform: parser.PlatformDingTalk, Content: strings.Repeat("stack frame\n", 20), Summary: strings.Repeat("s", 200)}
+	if err := mgr.Send(context.Background(), msg); err != nil {
+		t.Fatal(err)
+	}
+	if len(fake.contents) != 3 {
+		t.Errorf("Expected 3 parts, got %d", len(fake.contents))
+	}
+}
+
+func TestManagerSendOversizePartial(t *testing.T) {
+	content := strings.Repeat("stack frame\n", 20)
+	retryExtra := map[string]any{"touser": "lisi"}
+
+	tests := []struct {
+		name          string
+		errs          []error
+		wantFailed    []string
+		wantRetryable bool
+	}{
+		{
+			name:       "partial on first part",
+			errs:       []error{Partial([]string{"user:lisi"})},
+			wantFailed: []string{"user:lisi"},
+		},
+		{
+			name:          "same retry on every part",
+			errs:          []error{PartialRetry([]string{"user:lisi"}, retryExtra), PartialRetry([]string{"user:lisi"}, retryExtra), PartialRetry([]string{"user:lisi"}, retryExtra)},
+			wantFailed:    []string{"user:lisi"},
+			wantRetryable: true,
+		},
+		{
+			// 各部分未送达的接收人不同，无法只重试一组接收人
+			name:       "different recipients per part",
+			errs:       []error{PartialRetry([]string{"user:lisi"}, retryExtra), nil, Partial([]string{"user:wangwu"})},
+			wantFailed: []string{"user:lisi", "user:wangwu"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &limitedSender{limit: 120, policy: OversizeSplit, errs: tt.errs}
+			mgr := NewManager()
+			mgr.Register(parser.PlatformDingTalk, fake)
+
+			err := mgr.Send(context.Background(), &parser.Message{Platform: parser.PlatformDingTalk, Content: content})
+			if len(fake.contents) != 3 {
+				t.Fatalf("Expected all 3 parts to be sent, got %d", len(fake.contents))
+			}
+
+			var partial *PartialError
+			if !errors.As(err, &partial) {
+				t.Fatalf("Expected partial error, got %v", err)
+			}
+			if strings.Join(partial.Failed, ",") != strings.Join(tt.wantFailed, ",") {
+				t.Errorf("Expected failed %v, got %v", tt.wantFailed, partial.Failed)
+			}
+			if (partial.RetryExtra != nil) != tt.wantRetryable {
+				t.Errorf("Expected retry extra %t, got %v", tt.wantRetryable, partial.RetryExtra)
+			}
+		})
+	}
+}
+
+// TestManagerSendOversizeTitle 标题缺省取摘要的消息拆分后，每一部分都使用摘要作为标题
+func TestManagerSendOversizeTitle(t *testing.T) {
+	var titles []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var sent DingTalkMessage
+		json.NewDecoder(r.Body).Decode(&sent)
+		if sent.Markdown != nil {
+			titles = append(titles, sent.Markdown.Title)
+		}
+		w.Write([]byte(`{"errcode":0,"errmsg":"ok"}`))
+	}))
+	defer server.Close()
+
+	s, err := NewDingTalkSender(config.DingTalkConfig{AccessToken: "token", HTTP: config.HTTPConfig{BaseURL: server.URL}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mgr := NewManager()
+	mgr.Register(parser.PlatformDingTalk, s)
+
+	extra := map[string]any{"msgtype": DingTalkMsgMarkdown}
+	msg := &parser.Message{
+		Platform: parser.PlatformDingTalk,
+		Content:  strings.Repeat("- disk usage above threshold\n", 1500),
+		Summary:  "巡检报告",
+		Extra:    extra,
+	}
+	if err := mgr.Send(context.Background(), msg); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(titles) != 3 {
+		t.Fatalf("Expected 3 markdown parts, got %d", len(titles))
+	}
+	for i, title := range titles {
+		if title != "巡检报告" {
+			t.Errorf("Expected summary as title of part %d, got %q", i+1, title)
+		}
+	}
+	if _, ok := extra["title"]; ok {
+		t.Errorf("Expected original extra to be left unchanged, got %v", extra)
+	}
+}
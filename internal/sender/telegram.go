@@ -0,0 +1,389 @@
+package sender
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"notify/internal/config"
+	"notify/pkg/logger"
+
+	"go.uber.org/zap"
+)
+
+// telegramAPIBase Telegram Bot API 官方地址
+const telegramAPIBase = "https://api.telegram.org"
+
+// Telegram 支持的 parse_mode
+const (
+	TelegramParseHTML       = "HTML"
+	TelegramParseMarkdownV2 = "MarkdownV2"
+)
+
+const (
+	telegramTextLimit    = 4096             // 文本消息的最大长度
+	telegramCaptionLimit = 1024             // 图片和文件说明的最大长度
+	telegramPhotoMaxSize = 10 * 1024 * 1024 // 上传图片的最大字节数
+	telegramFileMaxSize  = 50 * 1024 * 1024 // 上传文件的最大字节数
+)
+
+// Telegram 消息类型，取自 extra["msgtype"]
+const (
+	telegramMsgTypeText  = "text"
+	telegramMsgTypePhoto = "photo"
+	telegramMsgTypeDoc   = "document"
+)
+
+// telegramHTMLEscaper HTML 模式下需要转义的字符
+var telegramHTMLEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// telegramMarkdownV2Special MarkdownV2 模式下需要用反斜杠转义的字符
+const telegramMarkdownV2Special = "\\_*[]()~`>#+-=|{}.!"
+
+type TelegramSender struct {
+	config  config.TelegramConfig
+	client  *http.Client
+	baseURL string
+}
+
+// telegramRequest 解析后的发送请求，按 chat_id 逐个发送
+type telegramRequest struct {
+	msgType    string
+	chatIDs    []string
+	text       string
+	parseMode  string
+	silent     bool
+	attachment *Attachment
+}
+
+type telegramResponse struct {
+	OK          bool   `json:"ok"`
+	ErrorCode   int    `json:"error_code"`
+	Description string `json:"description"`
+	Result      struct {
+		MessageID int64 `json:"message_id"`
+	} `json:"result"`
+	// Parameters 被限流时 retry_after 为需要等待的秒数
+	Parameters struct {
+		RetryAfter int `json:"retry_after"`
+	} `json:"parameters"`
+}
+
+func NewTelegramSender(config config.TelegramConfig) (*TelegramSender, error) {
+	if err := CheckOversize(config.Oversize); err != nil {
+		return nil, err
+	}
+	switch config.ParseMode {
+	case "":
+		config.ParseMode = TelegramParseHTML
+	case TelegramParseHTML, TelegramParseMarkdownV2:
+	default:
+		return nil, fmt.Errorf("invalid telegram parse_mode: %s", config.ParseMode)
+	}
+	client, err := NewHTTPClient(config.HTTP)
+	if err != nil {
+		return nil, fmt.Errorf("create telegram http client failed: %w", err)
+	}
+	return &TelegramSender{
+		config:  config,
+		client:  client,
+		baseURL: BaseURL(config.HTTP, telegramAPIBase),
+	}, nil
+}
+
+func (s *TelegramSender) Name() string {
+	return "telegram"
+}
+
+// ContentLimit 正文长度限制，图片和文件的说明文字限制更短
+//
+// 调用方通过 extra.parse_mode 提供的标记文本不做拆分或截断，避免拆断标记或插入未转义的编号。
+func (s *TelegramSender) ContentLimit(extra map[string]any) (int, string) {
+	if mode, _ := ExtraString(extra, "parse_mode"); mode != "" {
+		return 0, s.config.Oversize
+	}
+	msgType, _ := ExtraString(extra, "msgtype")
+	if msgType == telegramMsgTypePhoto || msgType == telegramMsgTypeDoc {
+		return telegramCaptionLimit, s.config.Oversize
+	}
+	return telegramTextLimit, s.config.Oversize
+}
+
+// Validate 校验消息类型、chat_id、parse_mode 和附件
+func (s *TelegramSender) Validate(content string, summary string, extra map[string]any) error {
+	_, err := s.buildRequest(content, summary, extra)
+	return err
+}
+
+// Send 依次发送给每个 chat_id，部分失败时只向可重试的失败会话重发
+func (s *TelegramSender) Send(ctx context.Context, content string, summary string, extra map[string]any) error {
+	req, err := s.buildRequest(content, summary, extra)
+	if err != nil {
+		return Permanent(err)
+	}
+	if s.config.BotToken == "" {
+		return Permanent(fmt.Errorf("telegram bot_token is required"))
+	}
+
+	var failed, retryChats []string
+	var firstErr error
+	var retryDelay time.Duration
+	for _, chatID := range req.chatIDs {
+		err := s.sendTo(ctx, req, chatID)
+		if err == nil {
+			continue
+		}
+		logger.Error("Failed to send Telegram message",
+			zap.String("chat_id", chatID),
+			zap.Error(err))
+		failed = append(failed, "chat:"+chatID)
+		if IsRetryable(err) {
+			retryChats = append(retryChats, chatID)
+			retryDelay = max(retryDelay, RetryDelay(err))
+		}
+		// 全部失败时返回的错误决定整条消息是否重试，优先保留可重试的错误
+		if firstErr == nil || (IsRetryable(err) && !IsRetryable(firstErr)) {
+			firstErr = err
+		}
+	}
+
+	switch {
+	case len(failed) == 0:
+		return nil
+	case len(failed) == len(req.chatIDs):
+		return firstErr
+	case len(retryChats) == 0:
+		return Partial(failed)
+	}
+
+	retryExtra := make(map[string]any, len(extra)+1)
+	for k, v := range extra {
+		retryExtra[k] = v
+	}
+	retryExtra["chat_id"] = retryChats
+	// 被限流的会话需等待 Telegram 要求的时间后再重试
+	return RetryAfter(PartialRetry(failed, retryExtra), retryDelay)
+}
+
+// sendTo 向单个会话发送消息，记录返回的 message_id 作为回执
+func (s *TelegramSender) sendTo(ctx context.Context, req *telegramRequest, chatID string) error {
+	var (
+		body        io.Reader
+		contentType string
+		method      string
+		err         error
+	)
+	switch req.msgType {
+	case telegramMsgTypePhoto:
+		method = "sendPhoto"
+	case telegramMsgTypeDoc:
+		method = "sendDocument"
+	default:
+		method = "sendMessage"
+	}
+	if req.attachment != nil && req.attachment.Data != nil {
+		body, contentType, err = s.multipartBody(req, chatID)
+	} else {
+		body, contentType, err = s.jsonBody(req, chatID)
+	}
+	if err != nil {
+		return Permanent(err)
+	}
+
+	url := s.baseURL + "/bot" + s.config.BotToken + "/" + method
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, body)
+	if err != nil {
+		return Permanent(fmt.Errorf("create request failed: %w", err))
+	}
+	httpReq.Header.Set("Content-Type", contentType)
+
+	resp, err := s.client.Do(httpReq)
+	if err != nil {
+		return Retryable(fmt.Errorf("send request failed: %w", err))
+	}
+	defer resp.Body.Close()
+
+	// 出错时 Bot API 在响应体中返回错误码和原因，无法解析时按 HTTP 状态码分类
+	var result telegramResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		if statusErr := CheckStatus(resp); statusErr != nil {
+			return statusErr
+		}
+		return Retryable(fmt.Errorf("decode response failed: %w", err))
+	}
+
+	if !result.OK {
+		err := fmt.Errorf("send message failed: %d %s", result.ErrorCode, result.Description)
+		if result.Parameters.RetryAfter > 0 {
+			return RetryAfter(err, time.Duration(result.Parameters.RetryAfter)*time.Second)
+		}
+		if result.ErrorCode == http.StatusTooManyRequests || result.ErrorCode >= http.StatusInternalServerError {
+			return Retryable(err)
+		}
+		return Permanent(err)
+	}
+
+	RecordReceipt(ctx, "chat:"+chatID, strconv.FormatInt(result.Result.MessageID, 10))
+	logger.Info("Telegram message sent successfully",
+		zap.String("chat_id", chatID),
+		zap.Int64("message_id", result.Result.MessageID))
+
+	return nil
+}
+
+// jsonBody 构造 JSON 请求体，附件以 URL 形式交给 Telegram 下载
+func (s *TelegramSender) jsonBody(req *telegramRequest, chatID string) (io.Reader, string, error) {
+	payload := map[string]any{"chat_id": chatID}
+	if req.parseMode != "" {
+		payload["parse_mode"] = req.parseMode
+	}
+	if req.silent {
+		payload["disable_notification"] = true
+	}
+	switch req.msgType {
+	case telegramMsgTypePhoto, telegramMsgTypeDoc:
+		payload[req.msgType] = req.attachment.URL
+		payload["caption"] = req.text
+	default:
+		payload["text"] = req.text
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, "", fmt.Errorf("marshal message failed: %w", err)
+	}
+	return bytes.NewReader(body), "application/json", nil
+}
+
+// multipartBody 构造上传附件内容的 multipart 请求体
+func (s *TelegramSender) multipartBody(req *telegramRequest, chatID string) (io.Reader, string, error) {
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+
+	fields := map[string]string{"chat_id": chatID, "caption": req.text}
+	if req.parseMode != "" {
+		fields["parse_mode"] = req.parseMode
+	}
+	if req.silent {
+		fields["disable_notification"] = "true"
+	}
+	for name, value := range fields {
+		if err := writer.WriteField(name, value); err != nil {
+			return nil, "", fmt.Errorf("write multipart field failed: %w", err)
+		}
+	}
+
+	part, err := writer.CreateFormFile(req.msgType, req.attachment.Name)
+	if err != nil {
+		return nil, "", fmt.Errorf("create multipart file failed: %w", err)
+	}
+	if _, err := part.Write(req.attachment.Data); err != nil {
+		return nil, "", fmt.Errorf("write multipart file failed: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return nil, "", fmt.Errorf("close multipart writer failed: %w", err)
+	}
+	return &buf, writer.FormDataContentType(), nil
+}
+
+// buildRequest 解析 extra 并渲染消息文本
+//
+// 会话取 extra["chat_id"]（单个或列表），未指定时使用配置的默认会话。
+// 默认将正文视为纯文本，按配置的 parse_mode 转义；extra["parse_mode"] 指定时正文视为该格式的标记，原样发送。
+// 摘要总是转义后加粗显示在正文之前。
+func (s *TelegramSender) buildRequest(content string, summary string, extra map[string]any) (*telegramRequest, error) {
+	req := &telegramRequest{}
+
+	var err error
+	if req.msgType, err = ExtraString(extra, "msgtype"); err != nil {
+		return nil, err
+	}
+	switch req.msgType {
+	case "":
+		req.msgType = telegramMsgTypeText
+	case telegramMsgTypeText, telegramMsgTypePhoto, telegramMsgTypeDoc:
+	default:
+		return nil, fmt.Errorf("unsupported telegram msgtype: %s", req.msgType)
+	}
+
+	if _, ok := extra["chat_id"]; ok {
+		if req.chatIDs, err = ExtraStrings(extra, "chat_id"); err != nil {
+			return nil, err
+		}
+	} else {
+		req.chatIDs = s.config.ChatIDs
+	}
+	if len(req.chatIDs) == 0 {
+		return nil, fmt.Errorf("telegram message requires extra.chat_id when no default chat_ids are configured")
+	}
+
+	if req.silent, err = ExtraBool(extra, "silent"); err != nil {
+		return nil, err
+	}
+
+	markup, err := ExtraString(extra, "parse_mode")
+	if err != nil {
+		return nil, err
+	}
+	req.parseMode = s.config.ParseMode
+	if markup != "" {
+		if markup != TelegramParseHTML && markup != TelegramParseMarkdownV2 {
+			return nil, fmt.Errorf("unsupported telegram parse_mode: %s", markup)
+		}
+		req.parseMode = markup
+	} else {
+		content = escapeTelegram(content, req.parseMode)
+	}
+	req.text = content
+	if summary != "" {
+		req.text = boldTelegram(escapeTelegram(summary, req.parseMode), req.parseMode) + "\n" + content
+	}
+
+	if req.msgType == telegramMsgTypeText {
+		return req, nil
+	}
+	if req.attachment, err = ParseAttachment(extra); err != nil {
+		return nil, err
+	}
+	if req.attachment == nil {
+		return nil, fmt.Errorf("telegram %s message requires extra.media_url or extra.media_base64", req.msgType)
+	}
+	maxSize := telegramFileMaxSize
+	if req.msgType == telegramMsgTypePhoto {
+		maxSize = telegramPhotoMaxSize
+	}
+	if len(req.attachment.Data) > maxSize {
+		return nil, fmt.Errorf("telegram %s exceeds %d bytes", req.msgType, maxSize)
+	}
+	return req, nil
+}
+
+// escapeTelegram 按 parse_mode 转义纯文本，使其原样显示
+func escapeTelegram(text string, parseMode string) string {
+	if parseMode == TelegramParseMarkdownV2 {
+		var b strings.Builder
+		for _, r := range text {
+			if strings.ContainsRune(telegramMarkdownV2Special, r) {
+				b.WriteByte('\\')
+			}
+			b.WriteRune(r)
+		}
+		return b.String()
+	}
+	return telegramHTMLEscaper.Replace(text)
+}
+
+// boldTelegram 将已转义的文本加粗
+func boldTelegram(text string, parseMode string) string {
+	if parseMode == TelegramParseMarkdownV2 {
+		return "*" + text + "*"
+	}
+	return "<b>" + text + "</b>"
+}
@@ -0,0 +1,195 @@
+package sender
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"notify/internal/config"
+)
+
+func TestTelegramEscape(t *testing.T) {
+	tests := []struct {
+		text      string
+		parseMode string
+		want      string
+	}{
+		{"a < b && c > d", TelegramParseHTML, "a &lt; b &amp;&amp; c &gt; d"},
+		{"v1.2 (beta) - done!", TelegramParseMarkdownV2, `v1\.2 \(beta\) \- done\!`},
+		{`C:\path_name *x*`, TelegramParseMarkdownV2, `C:\\path\_name \*x\*`},
+		// 中文等非保留字符原样保留
+		{"告警：磁盘#1", TelegramParseMarkdownV2, `告警：磁盘\#1`},
+	}
+
+	for _, tt := range tests {
+		if got := escapeTelegram(tt.text, tt.parseMode); got != tt.want {
+			t.Errorf("Expected %q, got %q", tt.want, got)
+		}
+	}
+}
+
+func TestTelegramSend(t *testing.T) {
+	tests := []struct {
+		name       string
+		summary    string
+		extra      map[string]any
+		wantPath   string
+		wantFields map[string]string
+		wantErr    bool
+	}{
+		{
+			name:       "default chats with escaped html",
+			summary:    "<告警>",
+			extra:      map[string]any{},
+			wantPath:   "/botTOKEN/sendMessage",
+			wantFields: map[string]string{"chat_id": "100", "parse_mode": "HTML", "text": "<b>&lt;告警&gt;</b>\na &lt; b"},
+		},
+		{
+			name:       "markdown markup to per-message chat silently",
+			extra:      map[string]any{"chat_id": float64(-1001), "parse_mode": "MarkdownV2", "silent": true},
+			wantPath:   "/botTOKEN/sendMessage",
+			wantFields: map[string]string{"chat_id": "-1001", "parse_mode": "MarkdownV2", "text": "a < b", "disable_notification": "true"},
+		},
+		{
+			name:       "photo by url",
+			extra:      map[string]any{"msgtype": "photo", "media_url": "https://example.com/a.png"},
+			wantPath:   "/botTOKEN/sendPhoto",
+			wantFields: map[string]string{"chat_id": "100", "photo": "https://example.com/a.png", "caption": "a &lt; b"},
+		},
+		{
+			name:       "uploaded document",
+			extra:      map[string]any{"msgtype": "document", "media_base64": "aGVsbG8=", "filename": "report.txt"},
+			wantPath:   "/botTOKEN/sendDocument",
+			wantFields: map[string]string{"chat_id": "100", "caption": "a &lt; b", "document": "hello"},
+		},
+		{
+			name:    "photo without attachment",
+			extra:   map[string]any{"msgtype": "photo"},
+			wantErr: true,
+		},
+		{
+			name:    "unsupported parse mode",
+			extra:   map[string]any{"parse_mode": "Markdown"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var path string
+			fields := map[string]string{}
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				path = r.URL.Path
+				if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/") {
+					r.ParseMultipartForm(1 << 20)
+					for k, v := range r.MultipartForm.Value {
+						fields[k] = v[0]
+					}
+					for k, v := range r.MultipartForm.File {
+						f, _ := v[0].Open()
+						data, _ := io.ReadAll(f)
+						fields[k] = string(data)
+					}
+				} else {
+					var payload map[string]any
+					json.NewDecoder(r.Body).Decode(&payload)
+					for k, v := range payload {
+						fields[k] = fmt.Sprint(v)
+					}
+				}
+				w.Write([]byte(`{"ok":true,"result":{"message_id":7}}`))
+			}))
+			defer server.Close()
+
+			s, err := NewTelegramSender(config.TelegramConfig{
+				BotToken: "TOKEN",
+				ChatIDs:  []string{"100"},
+				HTTP:     config.HTTPConfig{BaseURL: server.URL},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ctx, receipt := WithReceipt(context.Background())
+			err = s.Send(ctx, "a < b", tt.summary, tt.extra)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if path != tt.wantPath {
+				t.Errorf("Expected path %s, got %s", tt.wantPath, path)
+			}
+			for k, want := range tt.wantFields {
+				if fields[k] != want {
+					t.Errorf("Expected %s %q, got %q", k, want, fields[k])
+				}
+			}
+			if ids := receipt.IDs(); ids["chat:"+tt.wantFields["chat_id"]] != "7" {
+				t.Errorf("Expected receipt for chat %s, got %v", tt.wantFields["chat_id"], ids)
+			}
+		})
+	}
+}
+
+func TestTelegramSendPartial(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]any
+		json.NewDecoder(r.Body).Decode(&payload)
+		switch payload["chat_id"] {
+		case "1":
+			w.Write([]byte(`{"ok":true,"result":{"message_id":11}}`))
+		case "2":
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte(`{"ok":false,"error_code":429,"description":"Too Many Requests: retry after 3","parameters":{"retry_after":3}}`))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"ok":false,"error_code":400,"description":"Bad Request: chat not found"}`))
+		}
+	}))
+	defer server.Close()
+
+	s, err := NewTelegramSender(config.TelegramConfig{BotToken: "TOKEN", HTTP: config.HTTPConfig{BaseURL: server.URL}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = s.Send(context.Background(), "content", "", map[string]any{"chat_id": []any{"1", "2", "3"}})
+	var partial *PartialError
+	if !errors.As(err, &partial) {
+		t.Fatalf("Expected partial error, got %v", err)
+	}
+	if len(partial.Failed) != 2 {
+		t.Errorf("Expected 2 failed chats, got %v", partial.Failed)
+	}
+	// 只重试被限流的会话，不存在的会话不再重试
+	retry, _ := ExtraStrings(partial.RetryExtra, "chat_id")
+	if len(retry) != 1 || retry[0] != "2" {
+		t.Errorf("Expected retry to chat 2 only, got %v", retry)
+	}
+	// 按 parameters.retry_after 等待后再重试
+	if delay := RetryDelay(err); delay != 3*time.Second {
+		t.Errorf("Expected retry after 3s, got %v", delay)
+	}
+	err = s.Send(context.Background(), "content", "", map[string]any{"chat_id": "2"})
+	if !IsRetryable(err) || RetryDelay(err) != 3*time.Second {
+		t.Errorf("Expected retryable error after 3s, got %v (delay %v)", err, RetryDelay(err))
+	}
+
+	// 全部失败时按错误码决定是否重试
+	err = s.Send(context.Background(), "content", "", map[string]any{"chat_id": "3"})
+	if err == nil || IsRetryable(err) {
+		t.Errorf("Expected permanent error, got %v", err)
+	}
+}
@@ -9,19 +9,48 @@ import (
 	"time"
 
 	"notify/internal/config"
+	"notify/internal/sender"
 )
 
+// 企业微信错误码
+const (
+	weComErrSystemBusy   = -1
+	weComErrInvalidToken = 40014 // access_token 无效
+	weComErrTokenExpired = 42001 // access_token 已过期
+	weComErrFreqLimit    = 45009 // 接口调用超过限制
+	weComErrConcurrency  = 45033 // 接口并发调用超过限制
+)
+
+// isWeComTokenError 判断是否为 access_token 失效类错误，需要刷新 token 后重试
+func isWeComTokenError(code int) bool {
+	return code == weComErrInvalidToken || code == weComErrTokenExpired
+}
+
+// isWeComRetryable 判断企业微信错误码是否可以重试
+func isWeComRetryable(code int) bool {
+	switch code {
+	case weComErrSystemBusy, weComErrFreqLimit, weComErrConcurrency:
+		return true
+	default:
+		return isWeComTokenError(code)
+	}
+}
+
 // TokenManager 处理access token的获取和刷新
 type TokenManager struct {
 	config     config.WeComConfig
+	client     *http.Client
+	baseURL    string
 	token      string
 	tokenMutex sync.RWMutex
 	tokenExp   time.Time
 }
 
-func NewTokenManager(config config.WeComConfig) *TokenManager {
+func NewTokenManager(config config.WeComConfig, client *http.Client) *TokenManager {
 	return &TokenManager{
-		config: config,
+		config:  config,
+		client:  client,
+		baseURL: sender.BaseURL(config.HTTP, weComAPIBase),
 	}
 }
 
@@ -44,23 +73,27 @@ func (tm *TokenManager) GetToken(ctx context.Context) (string, error) {
 
 	// 企业微信的token获取接口
 	url := fmt.Sprintf(
-		"https://qyapi.weixin.qq.com/cgi-bin/gettoken?corpid=%s&corpsecret=%s",
+		"%s/cgi-bin/gettoken?corpid=%s&corpsecret=%s",
+		tm.baseURL,
 		tm.config.CorpID,
 		tm.config.AppSecret,
 	)
 
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
-		return "", fmt.Errorf("create request failed: %w", err)
+		return "", sender.Permanent(fmt.Errorf("create request failed: %w", err))
 	}
 
-	client := &http.Client{Timeout: 10 * time.Second}
-	resp, err := client.Do(req)
+	resp, err := tm.client.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("get access token failed: %w", err)
+		return "", sender.Retryable(fmt.Errorf("get access token failed: %w", err))
 	}
 	defer resp.Body.Close()
 
+	if err := sender.CheckStatus(resp); err != nil {
+		return "", err
+	}
+
 	var result struct {
 		ErrCode     int    `json:"errcode"`
 		ErrMsg      string `json:"errmsg"`
@@ -68,11 +101,16 @@ func (tm *TokenManager) GetToken(ctx context.Context) (string, error) {
 		ExpiresIn   int    `json:"expires_in"`
 	}
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return "", fmt.Errorf("decode response failed: %w", err)
+		return "", sender.Retryable(fmt.Errorf("decode response failed: %w", err))
 	}
 
 	if result.ErrCode != 0 {
-		return "", fmt.Errorf("get access token failed: %s", result.ErrMsg)
+		err := fmt.Errorf("get access token failed: [%d] %s", result.ErrCode, result.ErrMsg)
+		if isWeComRetryable(result.ErrCode) {
+			return "", sender.Retryable(err)
+		}
+		// corpid 或 secret 错误，重试无意义
+		return "", sender.Permanent(err)
 	}
 
 	tm.token = result.AccessToken
@@ -80,3 +118,14 @@ func (tm *TokenManager) GetToken(ctx context.Context) (string, error) {
 
 	return tm.token, nil
 }
+
+// Invalidate 清除缓存的 token，下次调用 GetToken 时重新获取
+func (tm *TokenManager) Invalidate(token string) {
+	tm.tokenMutex.Lock()
+	defer tm.tokenMutex.Unlock()
+
+	// 只清除失效的那个 token，避免覆盖其他协程刚刷新的结果
+	if tm.token == token {
+		tm.token = ""
+	}
+}
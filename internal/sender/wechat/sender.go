@@ -8,8 +8,9 @@ import (
 type WeChatSenderType string
 
 const (
-	SenderTypeWeCom    WeChatSenderType = "wecom"    // 企业微信
-	SenderTypeWxPusher WeChatSenderType = "wxpusher" // WxPusher
+	SenderTypeWeCom    WeChatSenderType = "wecom"     // 企业微信
+	SenderTypeWxPusher WeChatSenderType = "wxpusher"  // WxPusher
+	SenderTypeWeComBot WeChatSenderType = "wecom_bot" // 企业微信群机器人
 )
 
 // WeChatSender 微信发送器接口
@@ -5,94 +5,216 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"mime/multipart"
 	"net/http"
-	"time"
+	"strings"
 
 	"notify/internal/config"
+	"notify/internal/sender"
 	"notify/pkg/logger"
 
 	"go.uber.org/zap"
 )
 
+// weComAPIBase 企业微信官方接口地址
+const weComAPIBase = "https://qyapi.weixin.qq.com"
+
 type WeComSender struct {
 	tokenManager *TokenManager
 	config       config.WeComConfig
 	client       *http.Client
+	baseURL      string
+}
+
+// weComResponse 企业微信接口返回的公共字段
+type weComResponse struct {
+	ErrCode int    `json:"errcode"`
+	ErrMsg  string `json:"errmsg"`
 }
 
-func NewWeComSender(config config.WeComConfig) *WeComSender {
+func NewWeComSender(config config.WeComConfig) (*WeComSender, error) {
+	if err := sender.CheckOversize(config.Oversize); err != nil {
+		return nil, err
+	}
+	client, err := sender.NewHTTPClient(config.HTTP)
+	if err != nil {
+		return nil, fmt.Errorf("create wecom http client failed: %w", err)
+	}
 	return &WeComSender{
-		tokenManager: NewTokenManager(config),
+		tokenManager: NewTokenManager(config, client),
 		config:       config,
-		client:       &http.Client{Timeout: 10 * time.Second},
-	}
+		client:       client,
+		baseURL:      sender.BaseURL(config.HTTP, weComAPIBase),
+	}, nil
 }
 
 func (s *WeComSender) Type() WeChatSenderType {
 	return SenderTypeWeCom
 }
 
+func (s *WeComSender) Name() string {
+	return "wechat/" + string(SenderTypeWeCom)
+}
+
+// ContentLimit 文本、Markdown 和文本卡片描述的长度限制
+func (s *WeComSender) ContentLimit(extra map[string]any) (int, string) {
+	msgType, _ := sender.ExtraString(extra, "msgtype")
+	return weComContentLimits[msgType], s.config.Oversize
+}
+
+// SummaryInTitle 文本卡片的摘要作为标题发送，文本和 Markdown 消息的摘要写在正文前
+func (s *WeComSender) SummaryInTitle(extra map[string]any) bool {
+	msgType, _ := sender.ExtraString(extra, "msgtype")
+	return msgType == WeComMsgTextCard
+}
+
+// Validate 校验 extra 中指定的消息类型及其必填字段
+func (s *WeComSender) Validate(content string, summary string, extra map[string]any) error {
+	_, err := buildWeComMessage(content, summary, extra)
+	return err
+}
+
 func (s *WeComSender) Send(ctx context.Context, content string, summary string, extra map[string]any) error {
-	token, err := s.tokenManager.GetToken(ctx)
+	msg, err := buildWeComMessage(content, summary, extra)
 	if err != nil {
-		return fmt.Errorf("get token failed: %w", err)
+		return sender.Permanent(err)
 	}
+	msg.AgentID = s.config.AgentID
 
-	messageContent := content
-	if summary != "" {
-		messageContent = fmt.Sprintf("【%s】\n\n%s", summary, content)
+	token, err := s.tokenManager.GetToken(ctx)
+	if err != nil {
+		return fmt.Errorf("get token failed: %w", err)
 	}
 
-	msg := struct {
-		ToUser  string `json:"touser"`
-		MsgType string `json:"msgtype"`
-		AgentID string `json:"agentid"`
-		Text    struct {
-			Content string `json:"content"`
-		} `json:"text"`
-	}{
-		ToUser:  extra["user_id"].(string),
-		MsgType: "text",
-		AgentID: s.config.AgentID,
-		Text: struct {
-			Content string `json:"content"`
-		}{
-			Content: messageContent,
-		},
+	// 图片和文件需要先上传为临时素材
+	if msg.attachment != nil {
+		mediaID, err := s.uploadMedia(ctx, token, msg.MsgType, msg.attachment)
+		if err != nil {
+			return err
+		}
+		msg.setMediaID(mediaID)
 	}
 
 	body, err := json.Marshal(msg)
 	if err != nil {
-		return fmt.Errorf("marshal message failed: %w", err)
+		return sender.Permanent(fmt.Errorf("marshal message failed: %w", err))
 	}
 
-	url := fmt.Sprintf("https://qyapi.weixin.qq.com/cgi-bin/message/send?access_token=%s", token)
+	url := fmt.Sprintf("%s/cgi-bin/message/send?access_token=%s", s.baseURL, token)
 	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
 	if err != nil {
-		return fmt.Errorf("create request failed: %w", err)
+		return sender.Permanent(fmt.Errorf("create request failed: %w", err))
 	}
 	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := s.client.Do(req)
+	var result struct {
+		weComResponse
+		InvalidUser  string `json:"invaliduser"`
+		InvalidParty string `json:"invalidparty"`
+		InvalidTag   string `json:"invalidtag"`
+	}
+	if err := s.do(req, &result); err != nil {
+		return err
+	}
+	if err := s.check(token, "send message", result.weComResponse); err != nil {
+		return err
+	}
+
+	// 部分接收人无效时企业微信仍返回成功，其余接收人已收到消息
+	failed := weComInvalidRecipients("user", result.InvalidUser)
+	failed = append(failed, weComInvalidRecipients("party", result.InvalidParty)...)
+	failed = append(failed, weComInvalidRecipients("tag", result.InvalidTag)...)
+	if len(failed) > 0 {
+		return sender.Partial(failed)
+	}
+
+	logger.Info("WeCom message sent successfully",
+		zap.String("msgtype", msg.MsgType),
+		zap.String("touser", msg.ToUser),
+		zap.String("toparty", msg.ToParty),
+		zap.String("totag", msg.ToTag))
+
+	return nil
+}
+
+// weComInvalidRecipients 解析返回结果中以 "|" 分隔的无效接收人，并加上类型前缀
+func weComInvalidRecipients(kind string, ids string) []string {
+	var list []string
+	for _, id := range strings.Split(ids, "|") {
+		if id != "" {
+			list = append(list, kind+":"+id)
+		}
+	}
+	return list
+}
+
+// uploadMedia 通过临时素材接口上传附件，返回 media_id
+func (s *WeComSender) uploadMedia(ctx context.Context, token string, mediaType string, attachment *sender.Attachment) (string, error) {
+	if err := attachment.Fetch(ctx, s.client, weComMaxMediaSize(mediaType)); err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+	part, err := writer.CreateFormFile("media", attachment.Name)
 	if err != nil {
-		return fmt.Errorf("send request failed: %w", err)
+		return "", sender.Permanent(fmt.Errorf("create form file failed: %w", err))
+	}
+	part.Write(attachment.Data)
+	if err := writer.Close(); err != nil {
+		return "", sender.Permanent(fmt.Errorf("close multipart writer failed: %w", err))
 	}
-	defer resp.Body.Close()
+
+	url := fmt.Sprintf("%s/cgi-bin/media/upload?access_token=%s&type=%s", s.baseURL, token, mediaType)
+	req, err := http.NewRequestWithContext(ctx, "POST", url, &buf)
+	if err != nil {
+		return "", sender.Permanent(fmt.Errorf("create request failed: %w", err))
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
 
 	var result struct {
-		ErrCode int    `json:"errcode"`
-		ErrMsg  string `json:"errmsg"`
+		weComResponse
+		MediaID string `json:"media_id"`
 	}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return fmt.Errorf("decode response failed: %w", err)
+	if err := s.do(req, &result); err != nil {
+		return "", err
 	}
-
-	if result.ErrCode != 0 {
-		return fmt.Errorf("send message failed: %s", result.ErrMsg)
+	if err := s.check(token, "upload media", result.weComResponse); err != nil {
+		return "", err
 	}
+	return result.MediaID, nil
+}
 
-	logger.Info("WeCom message sent successfully",
-		zap.String("user_id", msg.ToUser))
+// do 发送请求并解析 JSON 响应
+func (s *WeComSender) do(req *http.Request, result any) error {
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return sender.Retryable(fmt.Errorf("send request failed: %w", err))
+	}
+	defer resp.Body.Close()
 
+	if err := sender.CheckStatus(resp); err != nil {
+		return err
+	}
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return sender.Retryable(fmt.Errorf("decode response failed: %w", err))
+	}
 	return nil
 }
+
+// check 根据错误码对企业微信的返回结果分类
+func (s *WeComSender) check(token string, action string, result weComResponse) error {
+	if result.ErrCode == 0 {
+		return nil
+	}
+
+	err := fmt.Errorf("%s failed: [%d] %s", action, result.ErrCode, result.ErrMsg)
+	if isWeComTokenError(result.ErrCode) {
+		// token 失效，清除缓存后由分发器重试
+		s.tokenManager.Invalidate(token)
+	}
+	if isWeComRetryable(result.ErrCode) {
+		return sender.Retryable(err)
+	}
+	return sender.Permanent(err)
+}
@@ -0,0 +1,301 @@
+package wechat
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+
+	"notify/internal/config"
+	"notify/internal/sender"
+	"notify/pkg/logger"
+
+	"go.uber.org/zap"
+)
+
+const (
+	weComBotMaxImageSize = 2 << 20  // 图片最大 2MB
+	weComBotMaxFileSize  = 20 << 20 // 文件最大 20MB
+)
+
+// weComBotContentLimits 群机器人各类型正文的最大字节数，未列出的类型不拆分
+var weComBotContentLimits = map[string]int{
+	"":               2048,
+	WeComMsgText:     2048,
+	WeComMsgMarkdown: 4096,
+}
+
+// WeComBotSender 企业微信群机器人，通过 webhook key 发送，无需 corp secret 和 access_token
+type WeComBotSender struct {
+	config  config.WeComBotConfig
+	client  *http.Client
+	baseURL string
+}
+
+type weComBotMessage struct {
+	MsgType  string         `json:"msgtype"`
+	Text     *weComBotText  `json:"text,omitempty"`
+	Markdown *weComContent  `json:"markdown,omitempty"`
+	Image    *weComBotImage `json:"image,omitempty"`
+	News     *weComNews     `json:"news,omitempty"`
+	File     *weComMedia    `json:"file,omitempty"`
+
+	// attachment 图片或文件消息的附件，发送时再读取内容
+	attachment *sender.Attachment
+}
+
+type weComBotText struct {
+	Content             string   `json:"content"`
+	MentionedList       []string `json:"mentioned_list,omitempty"`
+	MentionedMobileList []string `json:"mentioned_mobile_list,omitempty"`
+}
+
+type weComBotImage struct {
+	Base64 string `json:"base64"`
+	MD5    string `json:"md5"`
+}
+
+func NewWeComBotSender(config config.WeComBotConfig) (*WeComBotSender, error) {
+	if err := sender.CheckOversize(config.Oversize); err != nil {
+		return nil, err
+	}
+	client, err := sender.NewHTTPClient(config.HTTP)
+	if err != nil {
+		return nil, fmt.Errorf("create wecom bot http client failed: %w", err)
+	}
+	return &WeComBotSender{
+		config:  config,
+		client:  client,
+		baseURL: sender.BaseURL(config.HTTP, weComAPIBase),
+	}, nil
+}
+
+func (s *WeComBotSender) Type() WeChatSenderType {
+	return SenderTypeWeComBot
+}
+
+func (s *WeComBotSender) Name() string {
+	return "wechat/" + string(SenderTypeWeComBot)
+}
+
+// ContentLimit 文本和 Markdown 消息的正文长度限制
+func (s *WeComBotSender) ContentLimit(extra map[string]any) (int, string) {
+	msgType, _ := sender.ExtraString(extra, "msgtype")
+	return weComBotContentLimits[msgType], s.config.Oversize
+}
+
+// Validate 校验 extra 中指定的消息类型及其必填字段
+func (s *WeComBotSender) Validate(content string, summary string, extra map[string]any) error {
+	_, err := buildWeComBotMessage(content, summary, extra)
+	return err
+}
+
+func (s *WeComBotSender) Send(ctx context.Context, content string, summary string, extra map[string]any) error {
+	msg, err := buildWeComBotMessage(content, summary, extra)
+	if err != nil {
+		return sender.Permanent(err)
+	}
+
+	switch msg.MsgType {
+	case WeComMsgImage:
+		// 图片直接以 base64 和 md5 发送
+		if err := msg.attachment.Fetch(ctx, s.client, weComBotMaxImageSize); err != nil {
+			return err
+		}
+		sum := md5.Sum(msg.attachment.Data)
+		msg.Image = &weComBotImage{
+			Base64: base64.StdEncoding.EncodeToString(msg.attachment.Data),
+			MD5:    hex.EncodeToString(sum[:]),
+		}
+	case WeComMsgFile:
+		if msg.attachment != nil {
+			mediaID, err := s.uploadMedia(ctx, msg.attachment)
+			if err != nil {
+				return err
+			}
+			msg.File.MediaID = mediaID
+		}
+	}
+
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return sender.Permanent(fmt.Errorf("marshal message failed: %w", err))
+	}
+
+	url := fmt.Sprintf("%s/cgi-bin/webhook/send?key=%s", s.baseURL, s.config.Key)
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+	if err != nil {
+		return sender.Permanent(fmt.Errorf("create request failed: %w", err))
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	var result weComResponse
+	if err := s.do(req, &result); err != nil {
+		return err
+	}
+	if err := checkWeComBot("send message", result); err != nil {
+		return err
+	}
+
+	logger.Info("WeCom bot message sent successfully",
+		zap.String("msgtype", msg.MsgType))
+
+	return nil
+}
+
+// uploadMedia 通过群机器人的 upload_media 接口上传文件，返回 media_id
+func (s *WeComBotSender) uploadMedia(ctx context.Context, attachment *sender.Attachment) (string, error) {
+	if err := attachment.Fetch(ctx, s.client, weComBotMaxFileSize); err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+	part, err := writer.CreateFormFile("media", attachment.Name)
+	if err != nil {
+		return "", sender.Permanent(fmt.Errorf("create form file failed: %w", err))
+	}
+	part.Write(attachment.Data)
+	if err := writer.Close(); err != nil {
+		return "", sender.Permanent(fmt.Errorf("close multipart writer failed: %w", err))
+	}
+
+	url := fmt.Sprintf("%s/cgi-bin/webhook/upload_media?key=%s&type=file", s.baseURL, s.config.Key)
+	req, err := http.NewRequestWithContext(ctx, "POST", url, &buf)
+	if err != nil {
+		return "", sender.Permanent(fmt.Errorf("create request failed: %w", err))
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	var result struct {
+		weComResponse
+		MediaID string `json:"media_id"`
+	}
+	if err := s.do(req, &result); err != nil {
+		return "", err
+	}
+	if err := checkWeComBot("upload media", result.weComResponse); err != nil {
+		return "", err
+	}
+	return result.MediaID, nil
+}
+
+// do 发送请求并解析 JSON 响应
+func (s *WeComBotSender) do(req *http.Request, result any) error {
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return sender.Retryable(fmt.Errorf("send request failed: %w", err))
+	}
+	defer resp.Body.Close()
+
+	if err := sender.CheckStatus(resp); err != nil {
+		return err
+	}
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return sender.Retryable(fmt.Errorf("decode response failed: %w", err))
+	}
+	return nil
+}
+
+// checkWeComBot 根据错误码对群机器人的返回结果分类
+func checkWeComBot(action string, result weComResponse) error {
+	if result.ErrCode == 0 {
+		return nil
+	}
+
+	err := fmt.Errorf("%s failed: [%d] %s", action, result.ErrCode, result.ErrMsg)
+	if isWeComRetryable(result.ErrCode) && !isWeComTokenError(result.ErrCode) {
+		return sender.Retryable(err)
+	}
+	// key 无效或消息格式错误，重试无意义
+	return sender.Permanent(err)
+}
+
+// buildWeComBotMessage 根据 extra["msgtype"] 构建群机器人消息，缺省为文本消息
+//
+// 各类型使用的 extra 字段：
+//   - text: mentioned_list（成员ID，"@all" 提醒所有人）、mentioned_mobile_list
+//   - news: articles[{title, description, url, picurl}]
+//   - image: media_base64 或 media_url（最大 2MB）
+//   - file: media_id，或 media_base64/media_url 与 filename，由发送器上传
+func buildWeComBotMessage(content string, summary string, extra map[string]any) (*weComBotMessage, error) {
+	msgType, err := sender.ExtraString(extra, "msgtype")
+	if err != nil {
+		return nil, err
+	}
+	mentioned, err := sender.ExtraStrings(extra, "mentioned_list")
+	if err != nil {
+		return nil, err
+	}
+	mentionedMobiles, err := sender.ExtraStrings(extra, "mentioned_mobile_list")
+	if err != nil {
+		return nil, err
+	}
+	if (len(mentioned) > 0 || len(mentionedMobiles) > 0) && msgType != "" && msgType != WeComMsgText {
+		return nil, fmt.Errorf("wecom bot %s message does not support mentions", msgType)
+	}
+
+	msg := &weComBotMessage{MsgType: msgType}
+	switch msgType {
+	case "", WeComMsgText:
+		text := content
+		if summary != "" {
+			text = fmt.Sprintf("【%s】\n\n%s", summary, content)
+		}
+		msg.MsgType = WeComMsgText
+		msg.Text = &weComBotText{
+			Content:             text,
+			MentionedList:       mentioned,
+			MentionedMobileList: mentionedMobiles,
+		}
+
+	case WeComMsgMarkdown:
+		text := content
+		if summary != "" {
+			text = fmt.Sprintf("### %s\n\n%s", summary, content)
+		}
+		msg.Markdown = &weComContent{Content: text}
+
+	case WeComMsgNews:
+		if msg.News, err = parseWeComNews(extra); err != nil {
+			return nil, err
+		}
+
+	case WeComMsgImage:
+		if msg.attachment, err = sender.ParseAttachment(extra); err != nil {
+			return nil, err
+		}
+		if msg.attachment == nil {
+			return nil, fmt.Errorf("image message requires extra.media_base64 or extra.media_url")
+		}
+		if len(msg.attachment.Data) > weComBotMaxImageSize {
+			return nil, fmt.Errorf("image exceeds %d bytes", weComBotMaxImageSize)
+		}
+
+	case WeComMsgFile:
+		msg.File = &weComMedia{}
+		if msg.File.MediaID, err = sender.ExtraString(extra, "media_id"); err != nil {
+			return nil, err
+		}
+		if msg.File.MediaID == "" {
+			if msg.attachment, err = sender.ParseAttachment(extra); err != nil {
+				return nil, err
+			}
+			if msg.attachment == nil {
+				return nil, fmt.Errorf("file message requires extra.media_id, extra.media_base64 or extra.media_url")
+			}
+			if len(msg.attachment.Data) > weComBotMaxFileSize {
+				return nil, fmt.Errorf("file exceeds %d bytes", weComBotMaxFileSize)
+			}
+		}
+
+	default:
+		return nil, fmt.Errorf("unsupported wecom bot msgtype: %s", msgType)
+	}
+	return msg, nil
+}
@@ -0,0 +1,115 @@
+package wechat
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"notify/internal/config"
+)
+
+func TestWeComBotSend(t *testing.T) {
+	image := []byte("png-data")
+	sum := md5.Sum(image)
+
+	tests := []struct {
+		name  string
+		extra map[string]any
+		check func(t *testing.T, sent map[string]any)
+	}{
+		{
+			name:  "text with mentions",
+			extra: map[string]any{"mentioned_list": []any{"zhangsan", "@all"}, "mentioned_mobile_list": "13800000000"},
+			check: func(t *testing.T, sent map[string]any) {
+				text, _ := sent["text"].(map[string]any)
+				if list, _ := text["mentioned_list"].([]any); len(list) != 2 {
+					t.Errorf("Expected 2 mentioned users, got %v", text["mentioned_list"])
+				}
+				if list, _ := text["mentioned_mobile_list"].([]any); len(list) != 1 {
+					t.Errorf("Expected 1 mentioned mobile, got %v", text["mentioned_mobile_list"])
+				}
+			},
+		},
+		{
+			name:  "image with base64 and md5",
+			extra: map[string]any{"msgtype": "image", "media_base64": base64.StdEncoding.EncodeToString(image)},
+			check: func(t *testing.T, sent map[string]any) {
+				img, _ := sent["image"].(map[string]any)
+				if img["base64"] != base64.StdEncoding.EncodeToString(image) {
+					t.Errorf("Expected image base64, got %v", img["base64"])
+				}
+				if img["md5"] != hex.EncodeToString(sum[:]) {
+					t.Errorf("Expected md5 %s, got %v", hex.EncodeToString(sum[:]), img["md5"])
+				}
+			},
+		},
+		{
+			name:  "file uploaded through upload_media",
+			extra: map[string]any{"msgtype": "file", "media_base64": base64.StdEncoding.EncodeToString([]byte("a,b")), "filename": "report.csv"},
+			check: func(t *testing.T, sent map[string]any) {
+				file, _ := sent["file"].(map[string]any)
+				if file["media_id"] != "MEDIA_ID" {
+					t.Errorf("Expected media_id MEDIA_ID, got %v", sent["file"])
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sent map[string]any
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Query().Get("key") != "bot_key" {
+					t.Errorf("Expected key bot_key, got %s", r.URL.Query().Get("key"))
+				}
+				switch r.URL.Path {
+				case "/cgi-bin/webhook/upload_media":
+					if _, _, err := r.FormFile("media"); err != nil {
+						t.Errorf("Expected media form file, got %v", err)
+					}
+					w.Write([]byte(`{"errcode":0,"errmsg":"ok","type":"file","media_id":"MEDIA_ID"}`))
+				case "/cgi-bin/webhook/send":
+					json.NewDecoder(r.Body).Decode(&sent)
+					w.Write([]byte(`{"errcode":0,"errmsg":"ok"}`))
+				default:
+					t.Errorf("Unexpected request path %s", r.URL.Path)
+				}
+			}))
+			defer server.Close()
+
+			s, err := NewWeComBotSender(config.WeComBotConfig{Key: "bot_key", HTTP: config.HTTPConfig{BaseURL: server.URL}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := s.Send(context.Background(), "content", "", tt.extra); err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			tt.check(t, sent)
+		})
+	}
+}
+
+func TestBuildWeComBotMessageInvalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		extra map[string]any
+	}{
+		{name: "mentions on markdown", extra: map[string]any{"msgtype": "markdown", "mentioned_list": "zhangsan"}},
+		{name: "image without data", extra: map[string]any{"msgtype": "image"}},
+		{name: "news without articles", extra: map[string]any{"msgtype": "news"}},
+		{name: "unknown msgtype", extra: map[string]any{"msgtype": "textcard"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := buildWeComBotMessage("content", "", tt.extra); err == nil {
+				t.Errorf("Expected error, got nil")
+			}
+		})
+	}
+}
@@ -0,0 +1,272 @@
+package wechat
+
+import (
+	"fmt"
+	"strings"
+
+	"notify/internal/sender"
+)
+
+// 企业微信应用消息类型
+const (
+	WeComMsgText     = "text"
+	WeComMsgMarkdown = "markdown"
+	WeComMsgTextCard = "textcard"
+	WeComMsgNews     = "news"
+	WeComMsgImage    = "image"
+	WeComMsgFile     = "file"
+)
+
+const (
+	weComMaxImageSize = 10 << 20 // 图片最大 10MB
+	weComMaxFileSize  = 20 << 20 // 普通文件最大 20MB
+	weComMaxArticles  = 8
+
+	weComMaxUsers   = 1000 // 单条消息最多 1000 个成员
+	weComMaxParties = 100  // 最多 100 个部门
+	weComMaxTags    = 100  // 最多 100 个标签
+
+	// weComToAll 发送给应用可见范围内的全部成员
+	weComToAll = "@all"
+)
+
+// weComContentLimits 应用消息各类型正文的最大字节数，未列出的类型不拆分
+var weComContentLimits = map[string]int{
+	"":               2048,
+	WeComMsgText:     2048,
+	WeComMsgMarkdown: 2048,
+	WeComMsgTextCard: 512,
+}
+
+type weComMessage struct {
+	ToUser   string         `json:"touser,omitempty"`
+	ToParty  string         `json:"toparty,omitempty"`
+	ToTag    string         `json:"totag,omitempty"`
+	MsgType  string         `json:"msgtype"`
+	AgentID  string         `json:"agentid"`
+	Text     *weComContent  `json:"text,omitempty"`
+	Markdown *weComContent  `json:"markdown,omitempty"`
+	TextCard *weComTextCard `json:"textcard,omitempty"`
+	News     *weComNews     `json:"news,omitempty"`
+	Image    *weComMedia    `json:"image,omitempty"`
+	File     *weComMedia    `json:"file,omitempty"`
+
+	// attachment 图片和文件消息待上传的附件，上传后填入 media_id
+	attachment *sender.Attachment
+}
+
+type weComContent struct {
+	Content string `json:"content"`
+}
+
+type weComTextCard struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	URL         string `json:"url"`
+	BtnTxt      string `json:"btntxt,omitempty"`
+}
+
+type weComNews struct {
+	Articles []weComArticle `json:"articles"`
+}
+
+type weComArticle struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	URL         string `json:"url"`
+	PicURL      string `json:"picurl,omitempty"`
+}
+
+type weComMedia struct {
+	MediaID string `json:"media_id"`
+}
+
+// buildWeComMessage 根据 extra["msgtype"] 构建应用消息，缺省为文本消息
+//
+// 接收人由 touser（兼容 user_id）、toparty、totag 指定，至少需要一个，touser 为 "@all" 时发送给全部成员。
+// 各类型使用的 extra 字段：
+//   - textcard: title（缺省使用摘要）、url、btntxt
+//   - news: articles[{title, description, url, picurl}]
+//   - image/file: media_id，或 media_base64/media_url 与 filename，由发送器上传为临时素材
+func buildWeComMessage(content string, summary string, extra map[string]any) (*weComMessage, error) {
+	msgType, err := sender.ExtraString(extra, "msgtype")
+	if err != nil {
+		return nil, err
+	}
+
+	msg := &weComMessage{MsgType: msgType}
+	if err := msg.setRecipients(extra); err != nil {
+		return nil, err
+	}
+
+	switch msgType {
+	case "", WeComMsgText:
+		text := content
+		if summary != "" {
+			text = fmt.Sprintf("【%s】\n\n%s", summary, content)
+		}
+		msg.MsgType = WeComMsgText
+		msg.Text = &weComContent{Content: text}
+
+	case WeComMsgMarkdown:
+		text := content
+		if summary != "" {
+			text = fmt.Sprintf("### %s\n\n%s", summary, content)
+		}
+		msg.Markdown = &weComContent{Content: text}
+
+	case WeComMsgTextCard:
+		card := &weComTextCard{Description: content}
+		if card.Title, err = sender.ExtraString(extra, "title"); err != nil {
+			return nil, err
+		}
+		if card.Title == "" {
+			card.Title = summary
+		}
+		if card.URL, err = sender.ExtraString(extra, "url"); err != nil {
+			return nil, err
+		}
+		if card.BtnTxt, err = sender.ExtraString(extra, "btntxt"); err != nil {
+			return nil, err
+		}
+		if card.Title == "" || card.URL == "" {
+			return nil, fmt.Errorf("textcard message requires a title and extra.url")
+		}
+		msg.TextCard = card
+
+	case WeComMsgNews:
+		news, err := parseWeComNews(extra)
+		if err != nil {
+			return nil, err
+		}
+		msg.News = news
+
+	case WeComMsgImage, WeComMsgFile:
+		media := &weComMedia{}
+		if media.MediaID, err = sender.ExtraString(extra, "media_id"); err != nil {
+			return nil, err
+		}
+		if media.MediaID == "" {
+			if msg.attachment, err = sender.ParseAttachment(extra); err != nil {
+				return nil, err
+			}
+			if msg.attachment == nil {
+				return nil, fmt.Errorf("%s message requires extra.media_id, extra.media_base64 or extra.media_url", msgType)
+			}
+			if int64(len(msg.attachment.Data)) > weComMaxMediaSize(msgType) {
+				return nil, fmt.Errorf("%s exceeds %d bytes", msgType, weComMaxMediaSize(msgType))
+			}
+		}
+		if msgType == WeComMsgImage {
+			msg.Image = media
+		} else {
+			msg.File = media
+		}
+
+	default:
+		return nil, fmt.Errorf("unsupported wecom msgtype: %s", msgType)
+	}
+	return msg, nil
+}
+
+// parseWeComNews 读取图文消息的 articles，应用消息和群机器人共用
+func parseWeComNews(extra map[string]any) (*weComNews, error) {
+	articles, err := sender.ExtraObjects(extra, "articles")
+	if err != nil {
+		return nil, err
+	}
+	if len(articles) == 0 || len(articles) > weComMaxArticles {
+		return nil, fmt.Errorf("news message requires 1 to %d extra.articles", weComMaxArticles)
+	}
+	news := &weComNews{}
+	for i, item := range articles {
+		var article weComArticle
+		if article.Title, err = sender.ExtraString(item, "title"); err != nil {
+			return nil, err
+		}
+		if article.Description, err = sender.ExtraString(item, "description"); err != nil {
+			return nil, err
+		}
+		if article.URL, err = sender.ExtraString(item, "url"); err != nil {
+			return nil, err
+		}
+		if article.PicURL, err = sender.ExtraString(item, "picurl"); err != nil {
+			return nil, err
+		}
+		if article.Title == "" || article.URL == "" {
+			return nil, fmt.Errorf("extra.articles[%d] requires title and url", i)
+		}
+		news.Articles = append(news.Articles, article)
+	}
+	return news, nil
+}
+
+// weComMaxMediaSize 返回临时素材的大小上限
+func weComMaxMediaSize(msgType string) int64 {
+	if msgType == WeComMsgImage {
+		return weComMaxImageSize
+	}
+	return weComMaxFileSize
+}
+
+// setMediaID 填入上传后得到的 media_id
+func (m *weComMessage) setMediaID(mediaID string) {
+	if m.Image != nil {
+		m.Image.MediaID = mediaID
+	}
+	if m.File != nil {
+		m.File.MediaID = mediaID
+	}
+}
+
+// setRecipients 读取并校验接收人，多个ID以 "|" 连接
+func (m *weComMessage) setRecipients(extra map[string]any) error {
+	users, err := sender.ExtraStrings(extra, "touser")
+	if err != nil {
+		return err
+	}
+	legacy, err := sender.ExtraStrings(extra, "user_id")
+	if err != nil {
+		return err
+	}
+	users = append(users, legacy...)
+	parties, err := sender.ExtraStrings(extra, "toparty")
+	if err != nil {
+		return err
+	}
+	tags, err := sender.ExtraStrings(extra, "totag")
+	if err != nil {
+		return err
+	}
+
+	if len(users) == 0 && len(parties) == 0 && len(tags) == 0 {
+		return fmt.Errorf("wecom message requires extra.touser, extra.toparty or extra.totag")
+	}
+	for _, user := range users {
+		if user == weComToAll {
+			// @all 时忽略其他接收人
+			m.ToUser = weComToAll
+			return nil
+		}
+	}
+	switch {
+	case len(users) > weComMaxUsers:
+		return fmt.Errorf("extra.touser exceeds %d users", weComMaxUsers)
+	case len(parties) > weComMaxParties:
+		return fmt.Errorf("extra.toparty exceeds %d departments", weComMaxParties)
+	case len(tags) > weComMaxTags:
+		return fmt.Errorf("extra.totag exceeds %d tags", weComMaxTags)
+	}
+	for _, ids := range [][]string{users, parties, tags} {
+		for _, id := range ids {
+			if id == "" || strings.Contains(id, "|") {
+				return fmt.Errorf("invalid wecom recipient id: %q", id)
+			}
+		}
+	}
+
+	m.ToUser = strings.Join(users, "|")
+	m.ToParty = strings.Join(parties, "|")
+	m.ToTag = strings.Join(tags, "|")
+	return nil
+}
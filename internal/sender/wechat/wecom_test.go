@@ -0,0 +1,182 @@
+package wechat
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"notify/internal/config"
+	"notify/internal/sender"
+)
+
+// newTestWeComSender 创建指向测试服务器的发送器，并预置有效 token
+func newTestWeComSender(t *testing.T, baseURL string) *WeComSender {
+	t.Helper()
+	s, err := NewWeComSender(config.WeComConfig{AgentID: "1000002", HTTP: config.HTTPConfig{BaseURL: baseURL}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.tokenManager.token = "test_token"
+	s.tokenManager.tokenExp = time.Now().Add(time.Hour)
+	return s
+}
+
+func TestBuildWeComMessage(t *testing.T) {
+	tests := []struct {
+		name     string
+		extra    map[string]any
+		wantType string
+		wantErr  bool
+	}{
+		{name: "default text", wantType: WeComMsgText},
+		{name: "markdown", extra: map[string]any{"msgtype": "markdown"}, wantType: WeComMsgMarkdown},
+		{name: "textcard", extra: map[string]any{"msgtype": "textcard", "title": "告警", "url": "https://example.com"}, wantType: WeComMsgTextCard},
+		{name: "textcard without url", extra: map[string]any{"msgtype": "textcard", "title": "告警"}, wantErr: true},
+		{
+			name: "news",
+			extra: map[string]any{"msgtype": "news", "articles": []any{
+				map[string]any{"title": "日报", "url": "https://example.com"},
+			}},
+			wantType: WeComMsgNews,
+		},
+		{name: "news without articles", extra: map[string]any{"msgtype": "news"}, wantErr: true},
+		{name: "image with media_id", extra: map[string]any{"msgtype": "image", "media_id": "MEDIA"}, wantType: WeComMsgImage},
+		{name: "file with url", extra: map[string]any{"msgtype": "file", "media_url": "https://example.com/report.csv"}, wantType: WeComMsgFile},
+		{name: "image without media", extra: map[string]any{"msgtype": "image"}, wantErr: true},
+		{name: "image with invalid base64", extra: map[string]any{"msgtype": "image", "media_base64": "!!"}, wantErr: true},
+		{name: "unknown msgtype", extra: map[string]any{"msgtype": "voice"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			extra := map[string]any{"touser": "zhangsan"}
+			for k, v := range tt.extra {
+				extra[k] = v
+			}
+			msg, err := buildWeComMessage("content", "", extra)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if msg.MsgType != tt.wantType {
+				t.Errorf("Expected msgtype %s, got %s", tt.wantType, msg.MsgType)
+			}
+		})
+	}
+}
+
+func TestWeComSendImage(t *testing.T) {
+	var uploaded []byte
+	var sent map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/cgi-bin/media/upload":
+			if r.URL.Query().Get("type") != "image" {
+				t.Errorf("Expected upload type image, got %s", r.URL.Query().Get("type"))
+			}
+			file, header, err := r.FormFile("media")
+			if err != nil {
+				t.Fatalf("Expected media form file, got %v", err)
+			}
+			uploaded, _ = io.ReadAll(file)
+			if header.Filename != "dashboard.png" {
+				t.Errorf("Expected filename dashboard.png, got %s", header.Filename)
+			}
+			w.Write([]byte(`{"errcode":0,"errmsg":"ok","type":"image","media_id":"MEDIA_ID"}`))
+		case "/cgi-bin/message/send":
+			json.NewDecoder(r.Body).Decode(&sent)
+			w.Write([]byte(`{"errcode":0,"errmsg":"ok"}`))
+		default:
+			t.Errorf("Unexpected request path %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	s := newTestWeComSender(t, server.URL)
+	extra := map[string]any{
+		"user_id":      "zhangsan",
+		"msgtype":      "image",
+		"media_base64": base64.StdEncoding.EncodeToString([]byte("png-data")),
+		"filename":     "dashboard.png",
+	}
+	if err := s.Send(context.Background(), "", "", extra); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if string(uploaded) != "png-data" {
+		t.Errorf("Expected uploaded data png-data, got %q", uploaded)
+	}
+	image, _ := sent["image"].(map[string]any)
+	if image["media_id"] != "MEDIA_ID" {
+		t.Errorf("Expected media_id MEDIA_ID, got %v", sent["image"])
+	}
+}
+
+func TestWeComRecipients(t *testing.T) {
+	tests := []struct {
+		name      string
+		extra     map[string]any
+		wantUser  string
+		wantParty string
+		wantTag   string
+		wantErr   bool
+	}{
+		{name: "legacy user_id", extra: map[string]any{"user_id": "zhangsan"}, wantUser: "zhangsan"},
+		{name: "user list", extra: map[string]any{"touser": []any{"zhangsan", "lisi"}}, wantUser: "zhangsan|lisi"},
+		{name: "party and tag", extra: map[string]any{"toparty": []any{float64(2), "3"}, "totag": "1"}, wantParty: "2|3", wantTag: "1"},
+		{name: "all members", extra: map[string]any{"touser": []any{"zhangsan", "@all"}, "toparty": "2"}, wantUser: "@all"},
+		{name: "missing recipients", extra: map[string]any{}, wantErr: true},
+		{name: "user_id not a string", extra: map[string]any{"user_id": map[string]any{}}, wantErr: true},
+		{name: "id containing separator", extra: map[string]any{"touser": "a|b"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg, err := buildWeComMessage("content", "", tt.extra)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if msg.ToUser != tt.wantUser || msg.ToParty != tt.wantParty || msg.ToTag != tt.wantTag {
+				t.Errorf("Expected recipients %q/%q/%q, got %q/%q/%q",
+					tt.wantUser, tt.wantParty, tt.wantTag, msg.ToUser, msg.ToParty, msg.ToTag)
+			}
+		})
+	}
+}
+
+func TestWeComSendPartial(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"errcode":0,"errmsg":"ok","invaliduser":"lisi|wangwu","invalidparty":"9"}`))
+	}))
+	defer server.Close()
+
+	s := newTestWeComSender(t, server.URL)
+	err := s.Send(context.Background(), "content", "", map[string]any{"touser": []any{"zhangsan", "lisi", "wangwu"}, "toparty": "9"})
+
+	var partial *sender.PartialError
+	if !errors.As(err, &partial) {
+		t.Fatalf("Expected partial error, got %v", err)
+	}
+	want := []string{"user:lisi", "user:wangwu", "party:9"}
+	if strings.Join(partial.Failed, ",") != strings.Join(want, ",") {
+		t.Errorf("Expected failed recipients %v, got %v", want, partial.Failed)
+	}
+}
@@ -6,84 +6,290 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"time"
+	"strconv"
+	"strings"
 
 	"notify/internal/config"
+	"notify/internal/sender"
 	"notify/pkg/logger"
+	"notify/pkg/ratelimit"
 
 	"go.uber.org/zap"
 )
 
+// WxPusher 内容类型
+const (
+	wxPusherContentText     = 1
+	wxPusherContentHTML     = 2
+	wxPusherContentMarkdown = 3
+)
+
+// wxPusherAPIBase WxPusher 官方接口地址
+const wxPusherAPIBase = "https://wxpusher.zjiecode.com"
+
+// wxPusherContentLimit 正文的最大字节数
+const wxPusherContentLimit = 40000
+
+// wxPusherCodeSuccess WxPusher 表示成功的业务码
+const wxPusherCodeSuccess = 1000
+
+// wxPusherContentTypes 支持按名称指定内容类型
+var wxPusherContentTypes = map[string]int{
+	"text":     wxPusherContentText,
+	"html":     wxPusherContentHTML,
+	"markdown": wxPusherContentMarkdown,
+}
+
 type WxPusherSender struct {
-	config config.WxPusherConfig
-	client *http.Client
+	config  config.WxPusherConfig
+	client  *http.Client
+	apiURL  string
+	limiter *ratelimit.TokenBucket
 }
 
-func NewWxPusherSender(config config.WxPusherConfig) *WxPusherSender {
-	return &WxPusherSender{
-		config: config,
-		client: &http.Client{Timeout: 10 * time.Second},
+type wxPusherMessage struct {
+	AppToken      string   `json:"appToken"`
+	Content       string   `json:"content"`
+	Summary       string   `json:"summary,omitempty"`
+	ContentType   int      `json:"contentType"`
+	TopicIds      []int64  `json:"topicIds,omitempty"`
+	UIds          []string `json:"uids,omitempty"`
+	URL           string   `json:"url,omitempty"`
+	VerifyPayType int64    `json:"verifyPayType,omitempty"`
+}
+
+func NewWxPusherSender(config config.WxPusherConfig) (*WxPusherSender, error) {
+	if err := sender.CheckOversize(config.Oversize); err != nil {
+		return nil, err
 	}
+	client, err := sender.NewHTTPClient(config.HTTP)
+	if err != nil {
+		return nil, fmt.Errorf("create wxpusher http client failed: %w", err)
+	}
+
+	// api_url 为完整的发送接口地址，未配置时由 base_url 拼接
+	apiURL := config.ApiUrl
+	if apiURL == "" {
+		apiURL = sender.BaseURL(config.HTTP, wxPusherAPIBase) + "/api/send/message"
+	}
+	return &WxPusherSender{
+		config:  config,
+		client:  client,
+		apiURL:  apiURL,
+		limiter: ratelimit.NewTokenBucket(config.QPS),
+	}, nil
 }
 
 func (s *WxPusherSender) Type() WeChatSenderType {
 	return SenderTypeWxPusher
 }
 
-func (s *WxPusherSender) Send(ctx context.Context, content string, summary string, extra map[string]any) error {
-	msg := struct {
-		AppToken    string   `json:"appToken"`
-		Content     string   `json:"content"`
-		Summary     string   `json:"summary,omitempty"`
-		ContentType int      `json:"contentType"`
-		TopicIds    []int64  `json:"topicIds,omitempty"`
-		UIds        []string `json:"uids,omitempty"`
-	}{
-		AppToken:    s.config.AppToken,
-		Content:     content,
-		Summary:     summary,
-		ContentType: 1, // 1=文本
-		TopicIds:    s.config.TopicIDs,
-	}
+func (s *WxPusherSender) Name() string {
+	return "wechat/" + string(SenderTypeWxPusher)
+}
+
+// ContentLimit 正文长度限制
+func (s *WxPusherSender) ContentLimit(extra map[string]any) (int, string) {
+	return wxPusherContentLimit, s.config.Oversize
+}
+
+// SummaryInTitle 摘要作为消息卡片的摘要字段单独发送
+func (s *WxPusherSender) SummaryInTitle(extra map[string]any) bool {
+	return true
+}
 
-	// 如果指定了用户ID，添加到发送目标
-	if uid, ok := extra["user_id"].(string); ok {
-		msg.UIds = []string{uid}
+// Validate 校验内容类型、接收人等字段
+func (s *WxPusherSender) Validate(content string, summary string, extra map[string]any) error {
+	_, err := s.buildMessage(content, summary, extra)
+	return err
+}
+
+func (s *WxPusherSender) Send(ctx context.Context, content string, summary string, extra map[string]any) error {
+	msg, err := s.buildMessage(content, summary, extra)
+	if err != nil {
+		return sender.Permanent(err)
 	}
 
 	body, err := json.Marshal(msg)
 	if err != nil {
-		return fmt.Errorf("marshal message failed: %w", err)
+		return sender.Permanent(fmt.Errorf("marshal message failed: %w", err))
+	}
+
+	// 按配置的 QPS 限流，避免触发 WxPusher 的频率限制
+	if err := s.limiter.Wait(ctx); err != nil {
+		return err
 	}
 
-	// 使用配置的 API 地址
-	req, err := http.NewRequestWithContext(ctx, "POST", s.config.ApiUrl, bytes.NewReader(body))
+	req, err := http.NewRequestWithContext(ctx, "POST", s.apiURL, bytes.NewReader(body))
 	if err != nil {
-		return fmt.Errorf("create request failed: %w", err)
+		return sender.Permanent(fmt.Errorf("create request failed: %w", err))
 	}
 	req.Header.Set("Content-Type", "application/json")
 
 	resp, err := s.client.Do(req)
 	if err != nil {
-		return fmt.Errorf("send request failed: %w", err)
+		return sender.Retryable(fmt.Errorf("send request failed: %w", err))
 	}
 	defer resp.Body.Close()
 
+	if err := sender.CheckStatus(resp); err != nil {
+		return err
+	}
+
 	var result struct {
-		Code    int    `json:"code"`
-		Msg     string `json:"msg"`
-		Success bool   `json:"success"`
+		Code    int                    `json:"code"`
+		Msg     string                 `json:"msg"`
+		Success bool                   `json:"success"`
+		Data    []wxPusherTargetResult `json:"data"`
 	}
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return fmt.Errorf("decode response failed: %w", err)
+		return sender.Retryable(fmt.Errorf("decode response failed: %w", err))
 	}
 
 	if !result.Success {
-		return fmt.Errorf("send message failed: %s", result.Msg)
+		// WxPusher 的业务错误（如 appToken 或 uid 无效）重试无意义
+		return sender.Permanent(fmt.Errorf("send message failed: [%d] %s", result.Code, result.Msg))
+	}
+
+	if err := s.checkTargets(ctx, extra, result.Data); err != nil {
+		return err
 	}
 
 	logger.Info("WxPusher message sent successfully",
-		zap.Int64s("topic_ids", s.config.TopicIDs))
+		zap.Int("content_type", msg.ContentType),
+		zap.Strings("uids", msg.UIds),
+		zap.Int64s("topic_ids", msg.TopicIds))
 
 	return nil
 }
+
+// wxPusherTargetResult 每个 uid 或主题的发送结果
+type wxPusherTargetResult struct {
+	UID       string `json:"uid"`
+	TopicID   *int64 `json:"topicId"`
+	MessageID int64  `json:"messageId"`
+	Code      int    `json:"code"`
+	Status    string `json:"status"`
+}
+
+// recipient 返回结果对应的接收人标识，如 uid:UID_xxx、topic:123
+func (r wxPusherTargetResult) recipient() string {
+	if r.TopicID != nil {
+		return "topic:" + strconv.FormatInt(*r.TopicID, 10)
+	}
+	return "uid:" + r.UID
+}
+
+// checkTargets 记录每个接收人的消息ID，部分接收人失败时只针对这些接收人重试
+func (s *WxPusherSender) checkTargets(ctx context.Context, extra map[string]any, results []wxPusherTargetResult) error {
+	var failed []string
+	failedUIDs, failedTopics := []any{}, []any{}
+	for _, r := range results {
+		if r.Code == wxPusherCodeSuccess {
+			sender.RecordReceipt(ctx, r.recipient(), strconv.FormatInt(r.MessageID, 10))
+			continue
+		}
+		failed = append(failed, fmt.Sprintf("%s [%d] %s", r.recipient(), r.Code, r.Status))
+		if r.TopicID != nil {
+			failedTopics = append(failedTopics, *r.TopicID)
+		} else {
+			failedUIDs = append(failedUIDs, r.UID)
+		}
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+	if len(failed) == len(results) {
+		return sender.Retryable(fmt.Errorf("send message failed for all recipients: %s", strings.Join(failed, ", ")))
+	}
+
+	// 重试时只发送给失败的接收人，显式清空的 topic_ids 表示不再使用配置中的主题
+	retryExtra := make(map[string]any, len(extra)+2)
+	for k, v := range extra {
+		retryExtra[k] = v
+	}
+	delete(retryExtra, "user_id")
+	retryExtra["uids"] = failedUIDs
+	retryExtra["topic_ids"] = failedTopics
+	return sender.PartialRetry(failed, retryExtra)
+}
+
+// Close 停止限流器的令牌生成
+func (s *WxPusherSender) Close() error {
+	s.limiter.Stop()
+	return nil
+}
+
+// buildMessage 根据 extra 构建请求
+//
+// 支持的 extra 字段：
+//   - content_type: 1/"text"、2/"html"、3/"markdown"，默认为文本
+//   - uids: 用户ID列表，兼容旧的 user_id 字段
+//   - topic_ids: 主题ID列表，指定时覆盖配置中的主题，空列表表示不发送给任何主题
+//   - url: 点击消息后跳转的地址
+//   - verify_pay_type: 0 不验证，1 仅付费用户可收到，2 仅未订阅或订阅过期用户可收到
+func (s *WxPusherSender) buildMessage(content string, summary string, extra map[string]any) (*wxPusherMessage, error) {
+	msg := &wxPusherMessage{
+		AppToken:    s.config.AppToken,
+		Content:     content,
+		Summary:     summary,
+		ContentType: wxPusherContentText,
+		TopicIds:    s.config.TopicIDs,
+	}
+
+	contentType, err := parseWxPusherContentType(extra)
+	if err != nil {
+		return nil, err
+	}
+	msg.ContentType = contentType
+
+	uids, err := sender.ExtraStrings(extra, "uids")
+	if err != nil {
+		return nil, err
+	}
+	legacy, err := sender.ExtraStrings(extra, "user_id")
+	if err != nil {
+		return nil, err
+	}
+	msg.UIds = append(uids, legacy...)
+
+	topicIDs, err := sender.ExtraInts(extra, "topic_ids")
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := extra["topic_ids"]; ok {
+		msg.TopicIds = topicIDs
+	}
+
+	if msg.URL, err = sender.ExtraString(extra, "url"); err != nil {
+		return nil, err
+	}
+	if msg.VerifyPayType, err = sender.ExtraInt(extra, "verify_pay_type"); err != nil {
+		return nil, err
+	}
+	if msg.VerifyPayType < 0 || msg.VerifyPayType > 2 {
+		return nil, fmt.Errorf("extra.verify_pay_type must be 0, 1 or 2")
+	}
+
+	if len(msg.UIds) == 0 && len(msg.TopicIds) == 0 {
+		return nil, fmt.Errorf("wxpusher message requires extra.uids or topic ids")
+	}
+	return msg, nil
+}
+
+// parseWxPusherContentType 解析内容类型，支持数字和名称，未指定时为文本
+func parseWxPusherContentType(extra map[string]any) (int, error) {
+	if name, ok := extra["content_type"].(string); ok {
+		if contentType, ok := wxPusherContentTypes[name]; ok {
+			return contentType, nil
+		}
+	}
+	contentType, err := sender.ExtraInt(extra, "content_type")
+	if err != nil || contentType < 0 || contentType > wxPusherContentMarkdown {
+		return 0, fmt.Errorf("extra.content_type must be 1 (text), 2 (html) or 3 (markdown)")
+	}
+	if contentType == 0 {
+		return wxPusherContentText, nil
+	}
+	return int(contentType), nil
+}
@@ -0,0 +1,134 @@
+package wechat
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"notify/internal/config"
+	"notify/internal/sender"
+)
+
+func TestWxPusherSend(t *testing.T) {
+	tests := []struct {
+		name            string
+		extra           map[string]any
+		wantContentType int
+		wantUIds        int
+		wantTopicIds    []int64
+		wantErr         bool
+	}{
+		{
+			name:            "default text to configured topics",
+			extra:           map[string]any{},
+			wantContentType: wxPusherContentText,
+			wantTopicIds:    []int64{100},
+		},
+		{
+			name:            "markdown to multiple uids with topic override",
+			extra:           map[string]any{"content_type": "markdown", "uids": []any{"UID_a", "UID_b"}, "topic_ids": []any{float64(200), float64(300)}},
+			wantContentType: wxPusherContentMarkdown,
+			wantUIds:        2,
+			wantTopicIds:    []int64{200, 300},
+		},
+		{
+			name:            "html with legacy user_id",
+			extra:           map[string]any{"content_type": float64(2), "user_id": "UID_a", "url": "https://example.com", "verify_pay_type": float64(1)},
+			wantContentType: wxPusherContentHTML,
+			wantUIds:        1,
+			wantTopicIds:    []int64{100},
+		},
+		{
+			name:    "invalid content type",
+			extra:   map[string]any{"content_type": float64(4)},
+			wantErr: true,
+		},
+		{
+			name:    "invalid verify pay type",
+			extra:   map[string]any{"verify_pay_type": float64(3)},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sent wxPusherMessage
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				json.NewDecoder(r.Body).Decode(&sent)
+				w.Write([]byte(`{"code":1000,"msg":"处理成功","success":true}`))
+			}))
+			defer server.Close()
+
+			s, err := NewWxPusherSender(config.WxPusherConfig{AppToken: "AT_xxx", TopicIDs: []int64{100}, QPS: 10, ApiUrl: server.URL})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer s.Close()
+
+			err = s.Send(context.Background(), "content", "", tt.extra)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if sent.ContentType != tt.wantContentType {
+				t.Errorf("Expected content type %d, got %d", tt.wantContentType, sent.ContentType)
+			}
+			if len(sent.UIds) != tt.wantUIds {
+				t.Errorf("Expected %d uids, got %v", tt.wantUIds, sent.UIds)
+			}
+			if len(sent.TopicIds) != len(tt.wantTopicIds) || sent.TopicIds[0] != tt.wantTopicIds[0] {
+				t.Errorf("Expected topic ids %v, got %v", tt.wantTopicIds, sent.TopicIds)
+			}
+		})
+	}
+}
+
+func TestWxPusherSendPartial(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"code":1000,"msg":"处理成功","success":true,"data":[
+			{"uid":"UID_a","topicId":null,"messageId":101,"code":1000,"status":"创建发送任务成功"},
+			{"uid":"UID_b","topicId":null,"messageId":0,"code":1001,"status":"用户已取消关注"},
+			{"uid":null,"topicId":100,"messageId":102,"code":1000,"status":"创建发送任务成功"}
+		]}`))
+	}))
+	defer server.Close()
+
+	s, err := NewWxPusherSender(config.WxPusherConfig{AppToken: "AT_xxx", TopicIDs: []int64{100}, QPS: 10, ApiUrl: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	ctx, receipt := sender.WithReceipt(context.Background())
+	err = s.Send(ctx, "content", "", map[string]any{"uids": []any{"UID_a", "UID_b"}})
+
+	var partial *sender.PartialError
+	if !errors.As(err, &partial) {
+		t.Fatalf("Expected partial error, got %v", err)
+	}
+	if len(partial.Failed) != 1 {
+		t.Errorf("Expected 1 failed recipient, got %v", partial.Failed)
+	}
+
+	// 重试时只发送给失败的 uid，不再发送给已成功的主题
+	msg, err := s.buildMessage("content", "", partial.RetryExtra)
+	if err != nil {
+		t.Fatalf("Expected retry extra to be valid, got %v", err)
+	}
+	if len(msg.UIds) != 1 || msg.UIds[0] != "UID_b" || len(msg.TopicIds) != 0 {
+		t.Errorf("Expected retry to UID_b only, got uids %v topics %v", msg.UIds, msg.TopicIds)
+	}
+
+	ids := receipt.IDs()
+	if ids["uid:UID_a"] != "101" || ids["topic:100"] != "102" || len(ids) != 2 {
+		t.Errorf("Expected receipts for UID_a and topic 100, got %v", ids)
+	}
+}
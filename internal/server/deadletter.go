@@ -0,0 +1,132 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"notify/internal/dispatcher"
+	"notify/internal/parser"
+	"notify/internal/queue"
+	"notify/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// deadLetterResponse 死信消息的接口返回格式
+type deadLetterResponse struct {
+	ID         string          `json:"id"`
+	Message    *parser.Message `json:"message"`
+	EnqueuedAt time.Time       `json:"enqueued_at"`
+	FailedAt   time.Time       `json:"failed_at"`
+	LastError  string          `json:"last_error"`
+	Attempts   []queue.Attempt `json:"attempts"`
+}
+
+func newDeadLetterResponse(entry *queue.Entry) deadLetterResponse {
+	resp := deadLetterResponse{
+		ID:         entry.ID,
+		Message:    entry.Message,
+		EnqueuedAt: entry.EnqueuedAt,
+		Attempts:   entry.Attempts,
+	}
+	if n := len(entry.Attempts); n > 0 {
+		resp.FailedAt = entry.Attempts[n-1].At
+		resp.LastError = entry.Attempts[n-1].Error
+	}
+	return resp
+}
+
+func (s *Server) handleListDeadLetters(c *gin.Context) {
+	entries := s.dispatcher.DeadLetters()
+
+	items := make([]deadLetterResponse, 0, len(entries))
+	for _, entry := range entries {
+		items = append(items, newDeadLetterResponse(entry))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"count":       len(items),
+		"deadletters": items,
+	})
+}
+
+func (s *Server) handleGetDeadLetter(c *gin.Context) {
+	entry, ok := s.dispatcher.DeadLetter(c.Param("id"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Dead letter not found",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, newDeadLetterResponse(entry))
+}
+
+func (s *Server) handleReplayDeadLetter(c *gin.Context) {
+	id := c.Param("id")
+	if err := s.dispatcher.ReplayDeadLetter(id); err != nil {
+		s.deadLetterError(c, "Failed to replay dead letter", err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "Dead letter replayed",
+		"id":      id,
+	})
+}
+
+func (s *Server) handleReplayDeadLetters(c *gin.Context) {
+	count, err := s.dispatcher.ReplayDeadLetters()
+	if err != nil {
+		s.deadLetterError(c, "Failed to replay dead letters", err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "Dead letters replayed",
+		"count":   count,
+	})
+}
+
+func (s *Server) handlePurgeDeadLetter(c *gin.Context) {
+	id := c.Param("id")
+	if err := s.dispatcher.PurgeDeadLetter(id); err != nil {
+		s.deadLetterError(c, "Failed to purge dead letter", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Dead letter purged",
+		"id":      id,
+	})
+}
+
+func (s *Server) handlePurgeDeadLetters(c *gin.Context) {
+	count, err := s.dispatcher.PurgeDeadLetters()
+	if err != nil {
+		s.deadLetterError(c, "Failed to purge dead letters", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Dead letters purged",
+		"count":   count,
+	})
+}
+
+// deadLetterError 统一处理死信接口的错误响应
+func (s *Server) deadLetterError(c *gin.Context, msg string, err error) {
+	if errors.Is(err, dispatcher.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Dead letter not found",
+		})
+		return
+	}
+
+	logger.Error(msg, zap.Error(err))
+	c.JSON(http.StatusInternalServerError, gin.H{
+		"error": msg,
+	})
+}
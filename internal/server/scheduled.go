@@ -0,0 +1,66 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"notify/internal/dispatcher"
+	"notify/internal/parser"
+	"notify/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// scheduledResponse 定时消息的接口返回格式
+type scheduledResponse struct {
+	ID         string          `json:"id"`
+	SendAt     time.Time       `json:"send_at"`
+	EnqueuedAt time.Time       `json:"enqueued_at"`
+	Message    *parser.Message `json:"message"`
+}
+
+func (s *Server) handleListScheduled(c *gin.Context) {
+	entries := s.dispatcher.Scheduled()
+
+	items := make([]scheduledResponse, 0, len(entries))
+	for _, entry := range entries {
+		items = append(items, scheduledResponse{
+			ID:         entry.ID,
+			SendAt:     *entry.Message.SendAt,
+			EnqueuedAt: entry.EnqueuedAt,
+			Message:    entry.Message,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"count":     len(items),
+		"scheduled": items,
+	})
+}
+
+func (s *Server) handleCancelScheduled(c *gin.Context) {
+	id := c.Param("id")
+	err := s.dispatcher.Cancel(id)
+	switch {
+	case err == nil:
+		c.JSON(http.StatusOK, gin.H{
+			"message": "Scheduled message cancelled",
+			"id":      id,
+		})
+	case errors.Is(err, dispatcher.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Scheduled message not found",
+		})
+	case errors.Is(err, dispatcher.ErrNotScheduled):
+		c.JSON(http.StatusConflict, gin.H{
+			"error": "Message is already due and cannot be cancelled",
+		})
+	default:
+		logger.Error("Failed to cancel scheduled message", zap.String("id", id), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to cancel scheduled message",
+		})
+	}
+}
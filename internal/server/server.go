@@ -1,9 +1,14 @@
 package server
 
 import (
+	"context"
 	"crypto/subtle"
+	"errors"
 	"fmt"
+	"math"
 	"net/http"
+	"strconv"
+	"sync"
 	"time"
 
 	"notify/internal/config"
@@ -19,6 +24,9 @@ type Server struct {
 	config     config.ServerConfig
 	dispatcher *dispatcher.Dispatcher
 	engine     *gin.Engine
+
+	mu         sync.Mutex
+	httpServer *http.Server
 }
 
 func New(config config.ServerConfig, dispatcher *dispatcher.Dispatcher) *Server {
@@ -94,6 +102,27 @@ func (s *Server) registerRoutes() {
 
 		// notify 接口需要验证
 		v1.POST("/notify", s.authMiddleware(), s.handleNotify)
+
+		// 消息投递状态查询
+		v1.GET("/messages/:id", s.authMiddleware(), s.handleMessageStatus)
+
+		// 定时消息管理接口
+		scheduled := v1.Group("/scheduled", s.authMiddleware())
+		{
+			scheduled.GET("", s.handleListScheduled)
+			scheduled.DELETE("/:id", s.handleCancelScheduled)
+		}
+
+		// 死信管理接口
+		deadLetters := v1.Group("/deadletters", s.authMiddleware())
+		{
+			deadLetters.GET("", s.handleListDeadLetters)
+			deadLetters.GET("/:id", s.handleGetDeadLetter)
+			deadLetters.POST("/replay", s.handleReplayDeadLetters)
+			deadLetters.POST("/:id/replay", s.handleReplayDeadLetter)
+			deadLetters.DELETE("", s.handlePurgeDeadLetters)
+			deadLetters.DELETE("/:id", s.handlePurgeDeadLetter)
+		}
 	}
 }
 
@@ -106,6 +135,11 @@ func (s *Server) handleNotify(c *gin.Context) {
 		return
 	}
 
+	// 请求头中的幂等键优先于请求体
+	if key := c.GetHeader("Idempotency-Key"); key != "" {
+		msg.IdempotencyKey = key
+	}
+
 	// 验证消息
 	if err := msg.Validate(); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
@@ -113,13 +147,75 @@ func (s *Server) handleNotify(c *gin.Context) {
 		})
 		return
 	}
+	if err := s.dispatcher.Validate(&msg); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
 
 	// 分发消息
-	s.dispatcher.Dispatch(&msg)
+	if err := s.dispatcher.Dispatch(&msg); err != nil {
+		var dup *dispatcher.DuplicateError
+		if errors.As(err, &dup) {
+			s.duplicateResponse(c, dup.ID)
+			return
+		}
+		if errors.Is(err, dispatcher.ErrQueueFull) || errors.Is(err, dispatcher.ErrStopped) {
+			// 队列已满或服务正在关闭，告知客户端稍后重试
+			c.Header("Retry-After", s.retryAfter())
+			c.JSON(http.StatusServiceUnavailable, gin.H{
+				"error": "Service is busy, please retry later",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to queue message",
+		})
+		return
+	}
 
-	c.JSON(http.StatusAccepted, gin.H{
+	resp := gin.H{
 		"message": "Message accepted",
-	})
+		"id":      msg.ID,
+	}
+	if msg.SendAt != nil {
+		resp["send_at"] = msg.SendAt
+	}
+	c.JSON(http.StatusAccepted, resp)
+}
+
+func (s *Server) handleMessageStatus(c *gin.Context) {
+	status, ok := s.dispatcher.Status(c.Param("id"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Message not found",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+// duplicateResponse 返回重复请求对应的原消息ID及其当前状态
+func (s *Server) duplicateResponse(c *gin.Context, id string) {
+	resp := gin.H{
+		"message": "Duplicate request, message already accepted",
+		"id":      id,
+	}
+	if status, ok := s.dispatcher.Status(id); ok {
+		resp["status"] = status.State
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// retryAfter 返回 Retry-After 响应头的秒数
+func (s *Server) retryAfter() string {
+	retryAfter := s.config.RetryAfter
+	if retryAfter <= 0 {
+		retryAfter = 5 * time.Second
+	}
+	return strconv.Itoa(int(math.Ceil(retryAfter.Seconds())))
 }
 
 func (s *Server) handleHealth(c *gin.Context) {
@@ -141,5 +237,32 @@ func (s *Server) Start() error {
 		WriteTimeout: s.config.WriteTimeout,
 	}
 
-	return srv.ListenAndServe()
+	s.mu.Lock()
+	s.httpServer = srv
+	s.mu.Unlock()
+
+	// 调用 Shutdown 后 ListenAndServe 立即返回 ErrServerClosed，属于正常退出
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// Shutdown 停止接收新请求，并在 shutdown_timeout 内等待处理中的请求完成
+func (s *Server) Shutdown() error {
+	s.mu.Lock()
+	srv := s.httpServer
+	s.mu.Unlock()
+	if srv == nil {
+		return nil
+	}
+
+	timeout := s.config.ShutdownTimeout
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	return srv.Shutdown(ctx)
 }
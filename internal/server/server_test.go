@@ -6,9 +6,12 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"notify/internal/config"
 	"notify/internal/dispatcher"
+	"notify/internal/parser"
+	"notify/internal/queue"
 	"notify/internal/sender"
 )
 
@@ -19,7 +22,7 @@ func TestHandleHealth(t *testing.T) {
 		Mode: "debug",
 	}
 	senderMgr := sender.NewManager()
-	disp := dispatcher.New(100, 10, senderMgr)
+	disp := dispatcher.New(config.DispatcherConfig{BufferSize: 100, WorkerPoolSize: 10}, senderMgr, nil, nil, nil)
 	srv := New(cfg, disp)
 	srv.registerRoutes()
 
@@ -46,11 +49,17 @@ func TestHandleHealth(t *testing.T) {
 func TestHandleNotify(t *testing.T) {
 	// 创建测试服务器
 	cfg := config.ServerConfig{
-		Port: 8081,
-		Mode: "debug",
+		Port:  8081,
+		Mode:  "debug",
+		Token: "test_token",
 	}
 	senderMgr := sender.NewManager()
-	disp := dispatcher.New(100, 10, senderMgr)
+	dingTalkSender, err := sender.NewDingTalkSender(config.DingTalkConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	senderMgr.Register(parser.PlatformDingTalk, dingTalkSender)
+	disp := dispatcher.New(config.DispatcherConfig{BufferSize: 100, WorkerPoolSize: 10}, senderMgr, nil, nil, nil)
 	srv := New(cfg, disp)
 	srv.registerRoutes()
 
@@ -79,6 +88,35 @@ func TestHandleNotify(t *testing.T) {
 			},
 			wantStatus: http.StatusBadRequest,
 		},
+		{
+			name: "invalid priority",
+			payload: map[string]interface{}{
+				"platform": "wechat",
+				"content":  "test message",
+				"priority": "urgent",
+			},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name: "dingtalk markdown without title",
+			payload: map[string]interface{}{
+				"platform": "dingtalk",
+				"content":  "| a | b |",
+				"extra": map[string]interface{}{
+					"msgtype": "markdown",
+				},
+			},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name: "unknown channel",
+			payload: map[string]interface{}{
+				"platform": "dingtalk",
+				"channel":  "payments",
+				"content":  "test message",
+			},
+			wantStatus: http.StatusBadRequest,
+		},
 		{
 			name: "missing content",
 			payload: map[string]interface{}{
@@ -94,6 +132,56 @@ func TestHandleNotify(t *testing.T) {
 			w := httptest.NewRecorder()
 			req, _ := http.NewRequest("POST", "/api/v1/notify", bytes.NewBuffer(body))
 			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("X-API-Token", "test_token")
+			srv.engine.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("Expected status code %d, got %d", tt.wantStatus, w.Code)
+			}
+		})
+	}
+}
+
+func TestDeadLetterRoutes(t *testing.T) {
+	cfg := config.ServerConfig{
+		Port:  8081,
+		Mode:  "debug",
+		Token: "test_token",
+	}
+	senderMgr := sender.NewManager()
+	deadLetters := queue.NewMemoryStore()
+	deadLetters.Put(&queue.Entry{
+		ID:       "dead1",
+		Message:  &parser.Message{Platform: parser.PlatformWeChat, Content: "failed message"},
+		Attempts: []queue.Attempt{{At: time.Now(), Error: "invalid user"}},
+	})
+	disp := dispatcher.New(config.DispatcherConfig{BufferSize: 100, WorkerPoolSize: 10}, senderMgr, nil, deadLetters, nil)
+	srv := New(cfg, disp)
+	srv.registerRoutes()
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		token      string
+		wantStatus int
+	}{
+		{"missing token", "GET", "/api/v1/deadletters", "", http.StatusUnauthorized},
+		{"list", "GET", "/api/v1/deadletters", "test_token", http.StatusOK},
+		{"get", "GET", "/api/v1/deadletters/dead1", "test_token", http.StatusOK},
+		{"get unknown", "GET", "/api/v1/deadletters/unknown", "test_token", http.StatusNotFound},
+		{"replay unknown", "POST", "/api/v1/deadletters/unknown/replay", "test_token", http.StatusNotFound},
+		{"purge", "DELETE", "/api/v1/deadletters/dead1", "test_token", http.StatusOK},
+		{"get purged", "GET", "/api/v1/deadletters/dead1", "test_token", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(tt.method, tt.path, nil)
+			if tt.token != "" {
+				req.Header.Set("X-API-Token", tt.token)
+			}
 			srv.engine.ServeHTTP(w, req)
 
 			if w.Code != tt.wantStatus {
@@ -102,3 +190,224 @@ func TestHandleNotify(t *testing.T) {
 		})
 	}
 }
+
+func TestMessageStatus(t *testing.T) {
+	cfg := config.ServerConfig{
+		Port:  8081,
+		Mode:  "debug",
+		Token: "test_token",
+	}
+	senderMgr := sender.NewManager()
+	disp := dispatcher.New(config.DispatcherConfig{BufferSize: 100, WorkerPoolSize: 10}, senderMgr, nil, nil, nil)
+	srv := New(cfg, disp)
+	srv.registerRoutes()
+
+	// 提交消息并获取ID
+	body, _ := json.Marshal(map[string]interface{}{
+		"platform": "dingtalk",
+		"content":  "test message",
+	})
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/v1/notify", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-API-Token", "test_token")
+	srv.engine.ServeHTTP(w, req)
+
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("Expected status code %d, got %d", http.StatusAccepted, w.Code)
+	}
+	var accepted map[string]string
+	if err := json.Unmarshal(w.Body.Bytes(), &accepted); err != nil {
+		t.Fatal(err)
+	}
+	if accepted["id"] == "" {
+		t.Fatal("Expected message id in response")
+	}
+
+	// 查询状态
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/v1/messages/"+accepted["id"], nil)
+	req.Header.Set("X-API-Token", "test_token")
+	srv.engine.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, w.Code)
+	}
+	var status dispatcher.Status
+	if err := json.Unmarshal(w.Body.Bytes(), &status); err != nil {
+		t.Fatal(err)
+	}
+	if status.ID != accepted["id"] || status.State != dispatcher.StateQueued {
+		t.Errorf("Unexpected status: %+v", status)
+	}
+
+	// 未知ID
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/v1/messages/unknown", nil)
+	req.Header.Set("X-API-Token", "test_token")
+	srv.engine.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status code %d, got %d", http.StatusNotFound, w.Code)
+	}
+}
+
+func TestHandleNotifyQueueFull(t *testing.T) {
+	cfg := config.ServerConfig{
+		Port:       8081,
+		Mode:       "debug",
+		Token:      "test_token",
+		RetryAfter: 10 * time.Second,
+	}
+	senderMgr := sender.NewManager()
+	// 分发器未启动，缓冲区只能容纳一条消息
+	disp := dispatcher.New(config.DispatcherConfig{BufferSize: 1}, senderMgr, nil, nil, nil)
+	srv := New(cfg, disp)
+	srv.registerRoutes()
+
+	body, _ := json.Marshal(map[string]interface{}{
+		"platform": "dingtalk",
+		"content":  "test message",
+	})
+	wantStatus := []int{http.StatusAccepted, http.StatusServiceUnavailable}
+	for i, want := range wantStatus {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/api/v1/notify", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-API-Token", "test_token")
+		srv.engine.ServeHTTP(w, req)
+
+		if w.Code != want {
+			t.Fatalf("Request %d: expected status code %d, got %d", i, want, w.Code)
+		}
+		if want == http.StatusServiceUnavailable && w.Header().Get("Retry-After") != "10" {
+			t.Errorf("Expected Retry-After '10', got '%s'", w.Header().Get("Retry-After"))
+		}
+	}
+}
+
+func TestHandleNotifyIdempotency(t *testing.T) {
+	cfg := config.ServerConfig{
+		Port:  8081,
+		Mode:  "debug",
+		Token: "test_token",
+	}
+	senderMgr := sender.NewManager()
+	disp := dispatcher.New(config.DispatcherConfig{BufferSize: 100}, senderMgr, nil, nil, nil)
+	srv := New(cfg, disp)
+	srv.registerRoutes()
+
+	notify := func(payload map[string]interface{}, key string) (int, map[string]string) {
+		body, _ := json.Marshal(payload)
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/api/v1/notify", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-API-Token", "test_token")
+		if key != "" {
+			req.Header.Set("Idempotency-Key", key)
+		}
+		srv.engine.ServeHTTP(w, req)
+
+		var resp map[string]string
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		return w.Code, resp
+	}
+
+	payload := map[string]interface{}{"platform": "dingtalk", "content": "deploy finished"}
+
+	// 请求头中的幂等键
+	code, first := notify(payload, "job-42")
+	if code != http.StatusAccepted {
+		t.Fatalf("Expected status code %d, got %d", http.StatusAccepted, code)
+	}
+	code, second := notify(payload, "job-42")
+	if code != http.StatusOK {
+		t.Fatalf("Expected status code %d for duplicate, got %d", http.StatusOK, code)
+	}
+	if second["id"] != first["id"] {
+		t.Errorf("Expected original id '%s', got '%s'", first["id"], second["id"])
+	}
+	if second["status"] != string(dispatcher.StateQueued) {
+		t.Errorf("Expected status '%s', got '%s'", dispatcher.StateQueued, second["status"])
+	}
+
+	// 请求体中的幂等键
+	payload["idempotency_key"] = "cron-7"
+	if code, _ := notify(payload, ""); code != http.StatusAccepted {
+		t.Fatalf("Expected status code %d, got %d", http.StatusAccepted, code)
+	}
+	if code, _ := notify(payload, ""); code != http.StatusOK {
+		t.Errorf("Expected status code %d for duplicate, got %d", http.StatusOK, code)
+	}
+
+	// 不同的幂等键不受影响
+	if code, _ := notify(payload, "job-43"); code != http.StatusAccepted {
+		t.Errorf("Expected status code %d, got %d", http.StatusAccepted, code)
+	}
+}
+
+func TestScheduledRoutes(t *testing.T) {
+	cfg := config.ServerConfig{
+		Port:  8081,
+		Mode:  "debug",
+		Token: "test_token",
+	}
+	senderMgr := sender.NewManager()
+	disp := dispatcher.New(config.DispatcherConfig{BufferSize: 100}, senderMgr, nil, nil, nil)
+	srv := New(cfg, disp)
+	srv.registerRoutes()
+
+	serve := func(method, path string, payload map[string]interface{}) *httptest.ResponseRecorder {
+		var body []byte
+		if payload != nil {
+			body, _ = json.Marshal(payload)
+		}
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-API-Token", "test_token")
+		srv.engine.ServeHTTP(w, req)
+		return w
+	}
+
+	w := serve("POST", "/api/v1/notify", map[string]interface{}{
+		"platform": "dingtalk",
+		"content":  "maintenance notice",
+		"send_at":  time.Now().Add(time.Hour).Format(time.RFC3339),
+	})
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("Expected status code %d, got %d", http.StatusAccepted, w.Code)
+	}
+	var accepted map[string]string
+	json.Unmarshal(w.Body.Bytes(), &accepted)
+	if accepted["send_at"] == "" {
+		t.Error("Expected send_at in response")
+	}
+
+	w = serve("GET", "/api/v1/scheduled", nil)
+	var list struct {
+		Count int `json:"count"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &list)
+	if w.Code != http.StatusOK || list.Count != 1 {
+		t.Fatalf("Expected 1 scheduled message, got status %d count %d", w.Code, list.Count)
+	}
+
+	if w = serve("DELETE", "/api/v1/scheduled/"+accepted["id"], nil); w.Code != http.StatusOK {
+		t.Errorf("Expected status code %d, got %d", http.StatusOK, w.Code)
+	}
+	if w = serve("DELETE", "/api/v1/scheduled/"+accepted["id"], nil); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status code %d, got %d", http.StatusNotFound, w.Code)
+	}
+
+	// send_at 与 delay 不能同时指定
+	w = serve("POST", "/api/v1/notify", map[string]interface{}{
+		"platform": "dingtalk",
+		"content":  "maintenance notice",
+		"send_at":  time.Now().Add(time.Hour).Format(time.RFC3339),
+		"delay":    "10m",
+	})
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status code %d, got %d", http.StatusBadRequest, w.Code)
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"notify/internal/config"
 	"notify/internal/dispatcher"
 	"notify/internal/parser"
+	"notify/internal/queue"
 	"notify/internal/sender"
 	"notify/internal/server"
 	"notify/pkg/logger"
@@ -44,11 +45,75 @@ func main() {
 	}
 	senderMgr.Register(parser.PlatformWeChat, wechatSender)
 
-	// 注册钉钉发送器
-	senderMgr.Register(parser.PlatformDingTalk, sender.NewDingTalkSender(cfg.DingTalk))
+	// 创建并注册钉钉发送器
+	dingTalkSender, err := sender.NewDingTalkSender(cfg.DingTalk)
+	if err != nil {
+		log.Fatalf("Failed to create DingTalk sender: %v", err)
+	}
+	senderMgr.Register(parser.PlatformDingTalk, dingTalkSender)
+
+	// 创建并注册飞书发送器
+	feishuSender, err := sender.NewFeishuSender(cfg.Feishu)
+	if err != nil {
+		log.Fatalf("Failed to create Feishu sender: %v", err)
+	}
+	senderMgr.Register(parser.PlatformFeishu, feishuSender)
+
+	// 创建并注册 Slack 发送器
+	slackSender, err := sender.NewSlackSender(cfg.Slack)
+	if err != nil {
+		log.Fatalf("Failed to create Slack sender: %v", err)
+	}
+	senderMgr.Register(parser.PlatformSlack, slackSender)
+
+	// 创建并注册 Telegram 发送器
+	telegramSender, err := sender.NewTelegramSender(cfg.Telegram)
+	if err != nil {
+		log.Fatalf("Failed to create Telegram sender: %v", err)
+	}
+	senderMgr.Register(parser.PlatformTelegram, telegramSender)
+
+	// 创建并注册 Discord 发送器
+	discordSender, err := sender.NewDiscordSender(cfg.Discord)
+	if err != nil {
+		log.Fatalf("Failed to create Discord sender: %v", err)
+	}
+	senderMgr.Register(parser.PlatformDiscord, discordSender)
+
+	// 创建并注册邮件发送器
+	emailSender, err := sender.NewEmailSender(cfg.Email)
+	if err != nil {
+		log.Fatalf("Failed to create email sender: %v", err)
+	}
+	senderMgr.Register(parser.PlatformEmail, emailSender)
+
+	// 注册命名通道
+	for _, ch := range cfg.Channels {
+		channelSender, err := factory.CreateChannelSender(ch)
+		if err != nil {
+			log.Fatalf("Failed to create channel %s: %v", ch.Name, err)
+		}
+		if err := senderMgr.RegisterChannel(parser.Platform(ch.Platform), ch.Name, channelSender); err != nil {
+			log.Fatalf("Failed to register channel %s: %v", ch.Name, err)
+		}
+	}
+
+	// 打开持久化队列
+	store, err := queue.Open(cfg.Dispatcher.QueueDir, "pending")
+	if err != nil {
+		log.Fatalf("Failed to open message queue: %v", err)
+	}
+	deadLetters, err := queue.Open(cfg.Dispatcher.QueueDir, "deadletter")
+	if err != nil {
+		log.Fatalf("Failed to open dead letter queue: %v", err)
+	}
+	history, err := queue.Open(cfg.Dispatcher.QueueDir, "history")
+	if err != nil {
+		log.Fatalf("Failed to open message history: %v", err)
+	}
 
 	// 初始化分发器
-	disp := dispatcher.New(cfg.Dispatcher.BufferSize, cfg.Dispatcher.WorkerPoolSize, senderMgr)
+	disp := dispatcher.New(cfg.Dispatcher, senderMgr, store, deadLetters, history)
 
 	// 启动分发器
 	ctx, cancel := context.WithCancel(context.Background())
@@ -56,25 +121,43 @@ func main() {
 
 	// 初始化并启动服务器
 	srv := server.New(cfg.Server, disp)
+	serverErr := make(chan error, 1)
 	go func() {
-		if err := srv.Start(); err != nil {
-			logger.Error("Server error", zap.Error(err))
-			cancel()
-		}
+		serverErr <- srv.Start()
 	}()
 
 	// 初始化健康检查器
 	healthChecker := cron.NewHealthChecker(senderMgr, cfg.HealthCheck)
 	healthChecker.Start()
 
-	// 等待信号
+	// 等待信号或服务器异常退出
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
-	<-sigChan
+	select {
+	case sig := <-sigChan:
+		logger.Info("Shutting down", zap.String("signal", sig.String()))
+	case err := <-serverErr:
+		logger.Error("Server error, shutting down", zap.Error(err))
+	}
 
-	// 优雅关闭
-	healthChecker.Stop()
-	cancel()
+	// 优雅关闭：先停止接收请求，再排空消息队列，最后停止健康检查和发送器
+	if err := srv.Shutdown(); err != nil {
+		logger.Error("Failed to shutdown server gracefully", zap.Error(err))
+	}
 	disp.Stop()
+	cancel()
+	healthChecker.Stop()
+	if err := senderMgr.Close(); err != nil {
+		logger.Error("Failed to close senders", zap.Error(err))
+	}
+	if err := store.Close(); err != nil {
+		logger.Error("Failed to close message queue", zap.Error(err))
+	}
+	if err := deadLetters.Close(); err != nil {
+		logger.Error("Failed to close dead letter queue", zap.Error(err))
+	}
+	if err := history.Close(); err != nil {
+		logger.Error("Failed to close message history", zap.Error(err))
+	}
 	logger.Info("Server shutdown complete")
 }
@@ -14,7 +14,8 @@ type LogConfig struct {
 	Output string `mapstructure:"output"`
 }
 
-var log *zap.Logger
+// log 默认为空日志器，避免未调用 Init 时（如单元测试）出现空指针
+var log = zap.NewNop()
 
 func Init(cfg LogConfig) error {
 	config := zap.NewProductionConfig()